@@ -0,0 +1,101 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BootstrapOptions configures [Bootstrap].
+type BootstrapOptions struct {
+	// Override replaces the values of APP_WD and APP_ROOT which are already set.
+	Override bool
+	// WorkDir is the working directory of the application, the current directory by default.
+	WorkDir string
+	// RootDir is the root directory of the application, the directory of the executable by default.
+	// When the executable is built by go run or go test in a temporary directory,
+	// it is the root of the main module, the nearest directory holding a go.mod from WorkDir upward.
+	RootDir string
+}
+
+// Bootstrap sets APP_WD to the working directory of the application, and APP_ROOT to its root directory,
+// see [BootstrapOptions]. The values which are already set, by the operator for instance, are kept unless opts.Override is set,
+// and their directories are not resolved then: Bootstrap does not fail when the working directory or the executable
+// cannot be resolved, as in restricted sandboxes, if the variables are set.
+// The symbolic links to the executable are resolved, so that APP_ROOT is the directory of the actual executable.
+func (e *Env) Bootstrap(opts BootstrapOptions) error {
+	wd, setWD := e.lookup("APP_WD")
+	setWD = !setWD || opts.Override
+	if setWD {
+		var err error
+		if wd, err = workDir(opts.WorkDir); err != nil {
+			return fmt.Errorf("env: working directory: %w", err)
+		}
+	}
+	_, setRoot := e.lookup("APP_ROOT")
+	setRoot = !setRoot || opts.Override
+	var root string
+	if setRoot {
+		var err error
+		if root = opts.RootDir; root == "" {
+			if root, err = rootDir(wd); err != nil {
+				return fmt.Errorf("env: root directory: %w", err)
+			}
+		}
+		if root, err = filepath.Abs(root); err != nil {
+			return fmt.Errorf("env: root directory: %w", err)
+		}
+	}
+	if setWD {
+		if err := e.Set("APP_WD", wd); err != nil {
+			return err
+		}
+	}
+	if setRoot {
+		return e.Set("APP_ROOT", root)
+	}
+	return nil
+}
+
+// workDir returns the absolute path of the directory, or of the current directory if it is empty.
+func workDir(dir string) (string, error) {
+	if dir == "" {
+		return os.Getwd()
+	}
+	return filepath.Abs(dir)
+}
+
+// Bootstrap sets APP_WD and APP_ROOT in the environment, see [Env.Bootstrap].
+func Bootstrap(opts BootstrapOptions) error {
+	return std.Bootstrap(opts)
+}
+
+// rootDir returns the directory of the executable, or the root of the main module when it is built by go run or go test.
+func rootDir(wd string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", err
+	}
+	if isGoBuild(exe) {
+		if paths, err := search(wd, nil, []string{"go.mod"}); err == nil {
+			return filepath.Dir(paths[0]), nil
+		}
+	}
+	return filepath.Dir(exe), nil
+}
+
+// isGoBuild reports whether the executable is built in a temporary directory of the go command, such as
+// /tmp/go-build123/b001/exe/main: a directory named go-build followed by digits.
+func isGoBuild(exe string) bool {
+	for _, elem := range strings.Split(filepath.ToSlash(filepath.Dir(exe)), "/") {
+		digits, ok := strings.CutPrefix(elem, "go-build")
+		if ok && digits != "" && strings.Trim(digits, "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
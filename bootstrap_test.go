@@ -0,0 +1,74 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap(t *testing.T) {
+	module, err := os.Getwd()
+	assert.NoError(t, err)
+
+	t.Run("go test", func(t *testing.T) {
+		dir := filepath.Join(module, "testdata")
+		chdir(t, dir)
+		e := New()
+		assert.NoError(t, e.Bootstrap(BootstrapOptions{}))
+		assert.Equal(t, dir, e.Get("APP_WD"))
+		assert.Equal(t, module, e.Get("APP_ROOT"))
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		e := FromMap(map[string]string{"APP_ROOT": "/opt/app"})
+		assert.NoError(t, e.Bootstrap(BootstrapOptions{}))
+		assert.Equal(t, module, e.Get("APP_WD"))
+		assert.Equal(t, "/opt/app", e.Get("APP_ROOT"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		e := FromMap(map[string]string{"APP_WD": "/", "APP_ROOT": "/opt/app"})
+		assert.NoError(t, e.Bootstrap(BootstrapOptions{Override: true, WorkDir: "testdata", RootDir: "/srv/app"}))
+		assert.Equal(t, filepath.Join(module, "testdata"), e.Get("APP_WD"))
+		assert.Equal(t, "/srv/app", e.Get("APP_ROOT"))
+	})
+
+	t.Run("isolated from the environment", func(t *testing.T) {
+		t.Setenv("APP_WD", "/")
+		e := FromMap(map[string]string{"APP_WD": ""})
+		assert.NoError(t, e.Bootstrap(BootstrapOptions{}))
+		assert.Equal(t, module, e.Get("APP_ROOT"))
+	})
+
+	t.Run("deleted working directory", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("the working directory cannot be removed")
+		}
+		dir := filepath.Join(t.TempDir(), "deleted")
+		assert.NoError(t, os.Mkdir(dir, 0o700))
+		chdir(t, dir)
+		assert.NoError(t, os.Remove(dir))
+
+		e := FromMap(map[string]string{"APP_WD": "/srv/app", "APP_ROOT": "/opt/app"})
+		assert.NoError(t, e.Bootstrap(BootstrapOptions{}))
+		assert.Equal(t, "/srv/app", e.Get("APP_WD"))
+		assert.Equal(t, "/opt/app", e.Get("APP_ROOT"))
+
+		e = FromMap(map[string]string{"APP_ROOT": "/opt/app"})
+		assert.ErrorContains(t, e.Bootstrap(BootstrapOptions{}), "env: working directory")
+		assert.Empty(t, e.Get("APP_WD"))
+	})
+
+	t.Run("go build directory", func(t *testing.T) {
+		assert.True(t, isGoBuild("/tmp/go-build1234/b001/exe/main"))
+		assert.True(t, isGoBuild("/tmp/go-build1234/b001/env.test"))
+		assert.False(t, isGoBuild("/usr/local/bin/go-build"))
+		assert.False(t, isGoBuild("/opt/app/bin/app"))
+		assert.False(t, isGoBuild("/opt/go-builder/bin/app"))
+		assert.False(t, isGoBuild("/opt/go-build/bin/app"))
+		assert.False(t, isGoBuild("/opt/go-build-cache1/bin/app"))
+	})
+}
@@ -0,0 +1,95 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeyCase is the case the keys are converted to.
+type KeyCase int
+
+const (
+	// KeyCaseKeep keeps the keys as they are.
+	KeyCaseKeep KeyCase = iota
+	// KeyCaseUpper converts the keys to upper case, and replaces the characters which are not letters, digits or _ by _.
+	KeyCaseUpper
+	// KeyCaseLower converts the keys to lower case, and replaces the characters which are not letters, digits or _ by _.
+	KeyCaseLower
+)
+
+func (c KeyCase) convert(key string) string {
+	switch c {
+	case KeyCaseUpper:
+		return flatKey([]string{key}, "")
+	case KeyCaseLower:
+		return strings.ToLower(flatKey([]string{key}, ""))
+	}
+	return key
+}
+
+// DirOptions configures how a directory of key-per-file entries is loaded into an Env.
+// The keys are filtered and prefixed as configured by the embedded [LoadOptions], see LoadOptions.AddPrefix.
+type DirOptions struct {
+	LoadOptions
+	// Case is the case the file names are converted to.
+	Case KeyCase
+}
+
+// LoadDir loads the files of the directory into the Env: every file name is a key, and the content of the file its value,
+// without its trailing newlines. This is the layout of the ConfigMaps and Secrets mounted as volumes by Kubernetes.
+//
+// Hidden entries, whose name starts with a dot, are skipped. When the directory holds a ..data link,
+// as Kubernetes does to swap the content of a volume atomically, the files are read through the target of the link,
+// so that they all come from the same version of the volume. The files of the subdirectories are loaded as well,
+// their keys are the path of the files with the slashes replaced by _.
+func (e *Env) LoadDir(path string, opts DirOptions) error {
+	root := path
+	if target, err := filepath.EvalSymlinks(filepath.Join(path, "..data")); err == nil {
+		root = target
+	}
+	var batch []entry
+	if err := readDir(root, "", opts, &batch); err != nil {
+		return err
+	}
+	return e.apply(opts.LoadOptions, batch)
+}
+
+// readDir appends the files of the directory to the batch, prefixing their keys.
+func readDir(dir, prefix string, opts DirOptions, batch *[]entry) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, ent := range entries {
+		name := ent.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := readDir(path, prefix+name+"_", opts, batch); err != nil {
+				return err
+			}
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		*batch = append(*batch, entry{key: opts.Case.convert(prefix + name), value: strings.TrimRight(string(data), "\r\n")})
+	}
+	return nil
+}
+
+// LoadDir loads the files of the directory into the environment, see [Env.LoadDir].
+func LoadDir(path string, opts DirOptions) error {
+	return std.LoadDir(path, opts)
+}
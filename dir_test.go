@@ -0,0 +1,51 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDir(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "DB_HOST"), []byte("localhost\n"), 0o600))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("1"), 0o600))
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "cache"), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "cache", "url"), []byte("redis://"), 0o600))
+		e := FromMap(map[string]string{"DB_HOST": "db"})
+		assert.NoError(t, e.LoadDir(dir, DirOptions{}))
+		assert.Equal(t, map[string]string{"DB_HOST": "db", "cache_url": "redis://"}, e.Map())
+		assert.NoError(t, e.LoadDir(dir, DirOptions{LoadOptions: LoadOptions{Override: true}, Case: KeyCaseUpper}))
+		assert.Equal(t, map[string]string{"DB_HOST": "localhost", "CACHE_URL": "redis://", "cache_url": "redis://"}, e.Map())
+	})
+
+	t.Run("kubernetes", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic links")
+		}
+		dir := t.TempDir()
+		version := filepath.Join(dir, "..2024_01_01_00_00_00.1")
+		assert.NoError(t, os.MkdirAll(version, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(version, "db-password"), []byte("s3cr3t\n"), 0o600))
+		assert.NoError(t, os.WriteFile(filepath.Join(version, "db-user"), []byte("root"), 0o600))
+		assert.NoError(t, os.Symlink(filepath.Base(version), filepath.Join(dir, "..data")))
+		assert.NoError(t, os.Symlink("..data/db-password", filepath.Join(dir, "db-password")))
+		assert.NoError(t, os.Symlink("..data/db-user", filepath.Join(dir, "db-user")))
+
+		e := New()
+		assert.NoError(t, e.LoadDir(dir, DirOptions{LoadOptions: LoadOptions{AddPrefix: "APP_"}, Case: KeyCaseUpper}))
+		assert.Equal(t, map[string]string{"APP_DB_PASSWORD": "s3cr3t", "APP_DB_USER": "root"}, e.Map())
+
+		e = New()
+		assert.NoError(t, e.LoadDir(dir, DirOptions{Case: KeyCaseLower, LoadOptions: LoadOptions{Exclude: []string{"db_user"}}}))
+		assert.Equal(t, map[string]string{"db_password": "s3cr3t"}, e.Map())
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		assert.ErrorIs(t, New().LoadDir(filepath.Join(t.TempDir(), "missing"), DirOptions{}), os.ErrNotExist)
+	})
+}
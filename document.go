@@ -0,0 +1,303 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Document is a dotenv file which can be edited without losing its formatting.
+//
+// It keeps every line of the file, comments and blank lines included, and the entries with their original quoting.
+// The lines which are not edited are written back byte for byte.
+// When a key is defined several times, the last definition is the one which is read and edited.
+type Document struct {
+	bom   bool
+	lines []*docLine
+}
+
+// docLine is a line of a document, or the lines of a multi-line entry.
+type docLine struct {
+	raw string
+	// entry is false for comments and blank lines, the other fields are set for entries.
+	entry bool
+	key   string
+	value string
+	sp    span
+}
+
+// ParseDocument parses a dotenv file into a [Document], see [Parse] for the syntax.
+func ParseDocument(r io.Reader, opts ParseOptions) (*Document, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{bom: bytes.HasPrefix(src, bom)}
+	p := &dotenvParser{src: string(bytes.TrimPrefix(src, bom)), line: 1, opts: opts}
+	firstLines := make(map[string]int)
+	pos := 0
+	for {
+		ent, sp, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if first, ok := firstLines[ent.key]; ok {
+			if err := p.duplicate(ent, first); err != nil {
+				return nil, err
+			}
+		} else {
+			firstLines[ent.key] = ent.line
+		}
+		d.appendText(p.src[pos:sp.start])
+		d.lines = append(d.lines, &docLine{raw: p.src[sp.start:sp.end], entry: true, key: ent.key, value: ent.value, sp: sp.relative()})
+		pos = sp.end
+	}
+	d.appendText(p.src[pos:])
+	return d, nil
+}
+
+// appendText appends the comments and blank lines of s.
+func (d *Document) appendText(s string) {
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			d.lines = append(d.lines, &docLine{raw: line})
+		}
+	}
+}
+
+// Lookup returns the value of the key and whether it is defined.
+// The value is kept unexpanded.
+func (d *Document) Lookup(key string) (string, bool) {
+	if l := d.find(key); l != nil {
+		return l.value, true
+	}
+	return "", false
+}
+
+// Get returns the value of the key, or the empty string if it is not defined.
+func (d *Document) Get(key string) string {
+	value, _ := d.Lookup(key)
+	return value
+}
+
+// Keys returns the defined keys in the order of their first definition.
+func (d *Document) Keys() []string {
+	var keys []string
+	for _, l := range d.lines {
+		if l.entry && !slices.Contains(keys, l.key) {
+			keys = append(keys, l.key)
+		}
+	}
+	return keys
+}
+
+// Set sets the value of the key.
+// An existing definition is edited in place, keeping its quoting when it can hold the value,
+// its `export` prefix and its inline comment. A new key is appended to the document.
+func (d *Document) Set(key, value string) error {
+	if err := checkDotenvKey(key); err != nil {
+		return err
+	}
+	if l := d.find(key); l != nil {
+		l.setValue(value)
+		return nil
+	}
+	d.insert(len(d.lines), key, value)
+	return nil
+}
+
+// Delete removes every definition of the key, and reports whether it was defined.
+func (d *Document) Delete(key string) bool {
+	n := len(d.lines)
+	d.lines = slices.DeleteFunc(d.lines, func(l *docLine) bool {
+		return l.entry && l.key == key
+	})
+	return len(d.lines) < n
+}
+
+// Rename renames every definition of the key.
+// It fails if the key is not defined, or if the new key already is.
+func (d *Document) Rename(key, newKey string) error {
+	if err := checkDotenvKey(newKey); err != nil {
+		return err
+	}
+	if d.find(key) == nil {
+		return errors.New("env: undefined key " + key)
+	}
+	if d.find(newKey) != nil {
+		return errors.New("env: key " + newKey + " already defined")
+	}
+	for _, l := range d.lines {
+		if l.entry && l.key == key {
+			delta := len(newKey) - len(key)
+			l.raw = l.raw[:l.sp.keyStart] + newKey + l.raw[l.sp.keyEnd:]
+			l.key = newKey
+			l.sp.keyEnd += delta
+			l.sp.valueStart += delta
+			l.sp.valueEnd += delta
+			l.sp.end += delta
+		}
+	}
+	return nil
+}
+
+// InsertAfter inserts the definition of the key right after the last definition of another key.
+// It fails if the other key is not defined, or if the key already is.
+func (d *Document) InsertAfter(after, key, value string) error {
+	if err := checkDotenvKey(key); err != nil {
+		return err
+	}
+	i := slices.Index(d.lines, d.find(after))
+	if i < 0 {
+		return errors.New("env: undefined key " + after)
+	}
+	if d.find(key) != nil {
+		return errors.New("env: key " + key + " already defined")
+	}
+	d.insert(i+1, key, value)
+	return nil
+}
+
+// WriteTo writes the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, d.String())
+	return int64(n), err
+}
+
+// String returns the content of the document.
+func (d *Document) String() string {
+	var b strings.Builder
+	if d.bom {
+		b.Write(bom)
+	}
+	for _, l := range d.lines {
+		b.WriteString(l.raw)
+	}
+	return b.String()
+}
+
+// find returns the last definition of the key, or nil.
+func (d *Document) find(key string) *docLine {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if l := d.lines[i]; l.entry && l.key == key {
+			return l
+		}
+	}
+	return nil
+}
+
+// insert inserts a new entry at the index i of the lines.
+func (d *Document) insert(i int, key, value string) {
+	eol := d.eol()
+	if i > 0 && !strings.HasSuffix(d.lines[i-1].raw, "\n") {
+		d.lines[i-1].raw += eol
+	}
+	formatted := formatValue(value, 0)
+	l := &docLine{
+		raw:   key + "=" + formatted + eol,
+		entry: true,
+		key:   key,
+		value: value,
+		sp: span{
+			end:        len(key) + 1 + len(formatted) + len(eol),
+			keyEnd:     len(key),
+			valueStart: len(key) + 1,
+			valueEnd:   len(key) + 1 + len(formatted),
+			quote:      quoteOf(formatted),
+		},
+	}
+	d.lines = slices.Insert(d.lines, i, l)
+}
+
+// eol returns the line ending of the document, CRLF if its first line ends with it.
+func (d *Document) eol() string {
+	for _, l := range d.lines {
+		if strings.HasSuffix(l.raw, "\r\n") {
+			return "\r\n"
+		}
+		if strings.HasSuffix(l.raw, "\n") {
+			return "\n"
+		}
+	}
+	return "\n"
+}
+
+// setValue replaces the value of the entry.
+func (l *docLine) setValue(value string) {
+	formatted := formatValue(value, l.sp.quote)
+	rest := l.raw[l.sp.valueEnd:]
+	if l.sp.valueStart == l.sp.valueEnd && formatted != "" && strings.HasPrefix(rest, "#") {
+		// the blank before the inline comment of an empty value precedes the value, keep one after it
+		rest = " " + rest
+	}
+	l.raw = l.raw[:l.sp.valueStart] + formatted + rest
+	l.sp.end = len(l.raw)
+	l.sp.valueEnd = l.sp.valueStart + len(formatted)
+	l.value, l.sp.quote = value, quoteOf(formatted)
+}
+
+// formatValue formats a value so that parsing it gives back the value, and loading it does not expand it.
+// The value is written with the given quote when it can hold it, otherwise it is left unquoted
+// when it is safe to, or double-quoted. A value holding a dollar is always double-quoted, with the dollar escaped.
+func formatValue(value string, quote byte) string {
+	switch quote {
+	case '\'', '`':
+		if !strings.ContainsAny(value, string(quote)+"$") {
+			return string(quote) + value + string(quote)
+		}
+	case 0:
+		if isBareValue(value) {
+			return value
+		}
+	}
+	return doubleQuote(value)
+}
+
+// doubleQuote returns the value double-quoted, with the \n, \r, \t, \", \\ and \$ escape sequences,
+// so that the dollars are not expanded.
+func doubleQuote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '$':
+			b.WriteString(`\$`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isBareValue reports whether the value can be written unquoted, and is not expanded when it is loaded.
+func isBareValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if !isAlphaNum(value[i]) && !strings.ContainsRune("-.,:/@+=%[]|", rune(value[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteOf returns the quote of a formatted value, or zero when it is not quoted.
+func quoteOf(formatted string) byte {
+	if formatted != "" && strings.IndexByte("'`\"", formatted[0]) >= 0 {
+		return formatted[0]
+	}
+	return 0
+}
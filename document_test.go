@@ -0,0 +1,157 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocument(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		files, err := filepath.Glob("testdata/dotenv/*.env")
+		assert.NoError(t, err)
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			d, err := ParseDocument(strings.NewReader(string(data)), ParseOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, string(data), d.String(), file)
+			vars, err := Parse(strings.NewReader(string(data)), ParseOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, vars, ToMap(d), file)
+		}
+	})
+
+	src := `# database
+export DB_HOST=localhost # the host
+DB_USER='root'
+DB_PASS="secret"
+
+# cache
+CACHE=redis
+`
+
+	t.Run("get", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader(src), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"DB_HOST", "DB_USER", "DB_PASS", "CACHE"}, d.Keys())
+		assert.Equal(t, "localhost", d.Get("DB_HOST"))
+		_, ok := d.Lookup("MISSING")
+		assert.False(t, ok)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader(src), ParseOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, d.Set("DB_HOST", "db.internal"))
+		assert.NoError(t, d.Set("DB_USER", "it's me"))
+		assert.NoError(t, d.Set("DB_PASS", "new \"secret\"\n"))
+		assert.NoError(t, d.Set("NEW", "a b"))
+		assert.Error(t, d.Set("BAD KEY", "1"))
+		assert.Equal(t, `# database
+export DB_HOST=db.internal # the host
+DB_USER="it's me"
+DB_PASS="new \"secret\"\n"
+
+# cache
+CACHE=redis
+NEW="a b"
+`, d.String())
+		vars, err := Parse(strings.NewReader(d.String()), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"DB_HOST": "db.internal",
+			"DB_USER": "it's me",
+			"DB_PASS": "new \"secret\"\n",
+			"CACHE":   "redis",
+			"NEW":     "a b",
+		}, vars)
+	})
+
+	t.Run("set empty value with comment", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader("KEY= # c\nOTHER=#x\n"), ParseOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, d.Set("KEY", "new"))
+		assert.NoError(t, d.Set("OTHER", "y"))
+		assert.Equal(t, "KEY= new # c\nOTHER=y\n", d.String())
+		vars, err := Parse(strings.NewReader(d.String()), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"KEY": "new", "OTHER": "y"}, vars)
+		assert.NoError(t, d.Set("KEY", ""))
+		vars, err = Parse(strings.NewReader(d.String()), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"KEY": "", "OTHER": "y"}, vars)
+	})
+
+	t.Run("set escaped dollar", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader(`HOME_DIR="\$HOME"`+"\n"), ParseOptions{})
+		assert.NoError(t, err)
+		value, _ := d.Lookup("HOME_DIR")
+		assert.Equal(t, "$HOME", value)
+		assert.NoError(t, d.Set("HOME_DIR", value+"/app"))
+		assert.Equal(t, `HOME_DIR="\$HOME/app"`+"\n", d.String())
+	})
+
+	t.Run("set dollar in every quote style", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader("BARE=a\nSINGLE='a'\nBACKTICK=`a`\nDOUBLE=\"a\"\n"), ParseOptions{})
+		assert.NoError(t, err)
+		for _, key := range d.Keys() {
+			assert.NoError(t, d.Set(key, "${HOME}/x"))
+		}
+		assert.Equal(t, "BARE=\"\\${HOME}/x\"\nSINGLE=\"\\${HOME}/x\"\nBACKTICK=\"\\${HOME}/x\"\nDOUBLE=\"\\${HOME}/x\"\n", d.String())
+		e := FromMap(map[string]string{"HOME": "/root"})
+		assert.NoError(t, e.LoadFS(fstest.MapFS{".env": {Data: []byte(d.String())}}))
+		for _, key := range d.Keys() {
+			assert.Equal(t, "${HOME}/x", e.Get(key), key)
+		}
+	})
+
+	t.Run("delete and rename", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader(src), ParseOptions{})
+		assert.NoError(t, err)
+		assert.True(t, d.Delete("DB_PASS"))
+		assert.False(t, d.Delete("DB_PASS"))
+		assert.NoError(t, d.Rename("DB_HOST", "DATABASE_HOST"))
+		assert.NoError(t, d.Set("DATABASE_HOST", "127.0.0.1"))
+		assert.EqualError(t, d.Rename("MISSING", "OTHER"), "env: undefined key MISSING")
+		assert.EqualError(t, d.Rename("DB_USER", "CACHE"), "env: key CACHE already defined")
+		assert.Equal(t, `# database
+export DATABASE_HOST=127.0.0.1 # the host
+DB_USER='root'
+
+# cache
+CACHE=redis
+`, d.String())
+	})
+
+	t.Run("insert after", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader(src), ParseOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, d.InsertAfter("DB_USER", "DB_PORT", "3306"))
+		assert.EqualError(t, d.InsertAfter("MISSING", "A", "1"), "env: undefined key MISSING")
+		assert.EqualError(t, d.InsertAfter("DB_USER", "CACHE", "1"), "env: key CACHE already defined")
+		assert.Equal(t, []string{"DB_HOST", "DB_USER", "DB_PORT", "DB_PASS", "CACHE"}, d.Keys())
+	})
+
+	t.Run("line endings", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader("\uFEFFA=1\r\nB=\"x\r\ny\""), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "x\ny", d.Get("B"))
+		assert.NoError(t, d.Set("C", "3"))
+		assert.Equal(t, "\uFEFFA=1\r\nB=\"x\r\ny\"\r\nC=3\r\n", d.String())
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		d, err := ParseDocument(strings.NewReader("A=1\nA=2\n"), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "2", d.Get("A"))
+		assert.NoError(t, d.Set("A", "3"))
+		assert.Equal(t, "A=1\nA=3\n", d.String())
+		_, err = ParseDocument(strings.NewReader("A=1\nA=2\n"), ParseOptions{Duplicates: DuplicateError})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,400 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DuplicateMode configures how a key defined several times in the same dotenv file is handled.
+type DuplicateMode int
+
+const (
+	// DuplicateAllow silently keeps the last definition.
+	DuplicateAllow DuplicateMode = iota
+	// DuplicateWarn keeps the last definition and reports a warning.
+	DuplicateWarn
+	// DuplicateError fails the parsing.
+	DuplicateError
+)
+
+// ParseOptions configures the parsing of dotenv files.
+type ParseOptions struct {
+	// Filename is the name of the parsed file reported in the errors.
+	Filename string
+	// Duplicates configures how the keys defined several times are handled.
+	Duplicates DuplicateMode
+	// Warn receives the warnings. They are written to the standard logger when it is nil.
+	Warn func(err error)
+}
+
+func (opts ParseOptions) warn(err error) {
+	if opts.Warn != nil {
+		opts.Warn(err)
+	} else {
+		log.Print(err)
+	}
+}
+
+// ParseError is returned when a dotenv file cannot be parsed,
+// and reported as a warning for duplicated keys.
+type ParseError struct {
+	// Filename is the name of the parsed file, it may be empty.
+	Filename string
+	// Line and Column are the 1-based position of the error.
+	Line, Column int
+	// Snippet is the line of the error.
+	Snippet string
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	pos := fmt.Sprintf("%d:%d", e.Line, e.Column)
+	if e.Filename != "" {
+		pos = e.Filename + ":" + pos
+	}
+	return fmt.Sprintf("env: %s: %s: %s", pos, e.Msg, strconv.Quote(e.Snippet))
+}
+
+// Parse parses the variables of a dotenv file.
+//
+// The file holds one KEY=value assignment per line, optionally prefixed with `export `.
+// Blank lines and lines starting with # are ignored. Values are either:
+//   - unquoted: the rest of the line up to an inline comment starting with ` #`, trimmed
+//   - single-quoted or backtick-quoted: the literal content, which may span several lines
+//   - double-quoted: the content, which may span several lines, with the \n, \r, \t, \", \\ and \$ escape sequences.
+//     An escaped dollar is not expanded when the file is loaded with expansion
+//
+// Both LF and CRLF line endings are accepted, and so is a leading byte order mark.
+// The values are kept unexpanded, see [Expand].
+func Parse(r io.Reader, opts ParseOptions) (map[string]string, error) {
+	entries, err := parseEntries(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(entries))
+	for _, ent := range entries {
+		vars[ent.key] = ent.value
+	}
+	return vars, nil
+}
+
+// entry is a variable read from a dotenv file.
+type entry struct {
+	key   string
+	value string
+	// literal reports whether the value is single-quoted or backtick-quoted and must not be expanded.
+	literal bool
+	// expr is the value as the expansion reads it when it differs from the value,
+	// such as \$HOME for the double-quoted "\$HOME" whose value is $HOME.
+	expr string
+	// line is the 1-based line of the definition.
+	line int
+	// filename is the name of the file the entry is loaded from.
+	filename string
+}
+
+// expression returns the value as the expansion reads it.
+func (ent entry) expression() string {
+	if ent.expr != "" {
+		return ent.expr
+	}
+	return ent.value
+}
+
+// readEntries reads the named dotenv file.
+func readEntries(filename string, opts ParseOptions) ([]entry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	opts.Filename = filename
+	return parseEntries(f, opts)
+}
+
+// parseEntries parses the variables of a dotenv file in the order they are defined.
+// When a key is defined several times, only its last definition is kept, at the place of the first one.
+func parseEntries(r io.Reader, opts ParseOptions) ([]entry, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &dotenvParser{src: string(bytes.TrimPrefix(src, bom)), line: 1, opts: opts}
+	var entries []entry
+	index := make(map[string]int)
+	for {
+		ent, _, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return entries, nil
+		}
+		if i, ok := index[ent.key]; ok {
+			if err := p.duplicate(ent, entries[i].line); err != nil {
+				return nil, err
+			}
+			entries[i] = ent
+			continue
+		}
+		index[ent.key] = len(entries)
+		entries = append(entries, ent)
+	}
+}
+
+// duplicate reports the redefinition of a key first defined on the given line, as configured by the options.
+func (p *dotenvParser) duplicate(ent entry, first int) error {
+	err := &ParseError{
+		Filename: p.opts.Filename,
+		Line:     ent.line,
+		Column:   1,
+		Snippet:  p.lineAt(ent.line),
+		Msg:      fmt.Sprintf("duplicate key %s, first defined on line %d", ent.key, first),
+	}
+	switch p.opts.Duplicates {
+	case DuplicateError:
+		return err
+	case DuplicateWarn:
+		p.opts.warn(err)
+	}
+	return nil
+}
+
+// bom is the UTF-8 byte order mark.
+var bom = []byte("\uFEFF")
+
+// span holds the byte offsets of an entry in the parsed source.
+// The entry spans src[start:end], including the indentation and the line ending,
+// and quote is the quote of the value, or zero when it is not quoted.
+type span struct {
+	start, end           int
+	keyStart, keyEnd     int
+	valueStart, valueEnd int
+	quote                byte
+}
+
+// relative returns the span with offsets relative to its start.
+func (sp span) relative() span {
+	return span{
+		end:        sp.end - sp.start,
+		keyStart:   sp.keyStart - sp.start,
+		keyEnd:     sp.keyEnd - sp.start,
+		valueStart: sp.valueStart - sp.start,
+		valueEnd:   sp.valueEnd - sp.start,
+		quote:      sp.quote,
+	}
+}
+
+// dotenvParser reads the entries of a dotenv file one at a time.
+type dotenvParser struct {
+	src  string
+	pos  int
+	line int
+	// lineStart is the offset of the current line.
+	lineStart int
+	opts      ParseOptions
+}
+
+// next returns the next entry and its offsets, or false at the end of the input.
+func (p *dotenvParser) next() (entry, span, bool, error) {
+	for {
+		p.skipSpaces()
+		if p.pos == len(p.src) {
+			return entry{}, span{}, false, nil
+		}
+		if p.atEOL() || p.src[p.pos] == '#' {
+			p.skipLine()
+			continue
+		}
+		break
+	}
+	sp := span{start: p.lineStart}
+	if strings.HasPrefix(p.src[p.pos:], "export") && p.pos+6 < len(p.src) && (p.src[p.pos+6] == ' ' || p.src[p.pos+6] == '\t') {
+		p.pos += 6
+		p.skipSpaces()
+	}
+	ent := entry{line: p.line}
+	sp.keyStart = p.pos
+	for p.pos < len(p.src) && isKeyChar(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == sp.keyStart {
+		return entry{}, span{}, false, p.errorf("invalid character %q in key", p.char())
+	}
+	sp.keyEnd = p.pos
+	ent.key = p.src[sp.keyStart:sp.keyEnd]
+	p.skipSpaces()
+	if p.pos == len(p.src) || p.src[p.pos] != '=' {
+		if !p.atEOL() {
+			return entry{}, span{}, false, p.errorf("invalid character %q in key", p.char())
+		}
+		return entry{}, span{}, false, p.errorf("missing '=' after key %s", ent.key)
+	}
+	p.pos++
+	p.skipSpaces()
+	sp.valueStart = p.pos
+	if p.pos < len(p.src) {
+		switch q := p.src[p.pos]; q {
+		case '\'', '`', '"':
+			value, expr, err := p.quoted(q)
+			if err != nil {
+				return entry{}, span{}, false, err
+			}
+			ent.value, ent.expr, ent.literal = value, expr, q != '"'
+			sp.valueEnd, sp.quote = p.pos, q
+			p.skipSpaces()
+			if !p.atEOL() && p.src[p.pos] != '#' {
+				return entry{}, span{}, false, p.errorf("unexpected character %q after quoted value", p.char())
+			}
+			p.skipLine()
+			sp.end = p.pos
+			return ent, sp, true, nil
+		}
+	}
+	for !p.atEOL() {
+		// a # starts a comment only after a blank, so the value of H=#hash is #hash
+		if p.src[p.pos] == '#' && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		p.pos++
+	}
+	ent.value = strings.TrimRight(p.src[sp.valueStart:p.pos], " \t")
+	sp.valueEnd = sp.valueStart + len(ent.value)
+	p.skipLine()
+	sp.end = p.pos
+	return ent, sp, true, nil
+}
+
+// quoted reads a value enclosed in the quote q, the position being on the opening quote.
+// It returns the value, and the value as the expansion reads it if it holds escaped dollars.
+func (p *dotenvParser) quoted(q byte) (string, string, error) {
+	line, lineStart, open := p.line, p.lineStart, p.pos
+	p.pos++
+	var b strings.Builder
+	// dollars are the offsets of the escaped dollars in the value
+	var dollars []int
+	for ; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		switch {
+		case c == q:
+			p.pos++
+			value := b.String()
+			if len(dollars) == 0 {
+				return value, "", nil
+			}
+			var expr strings.Builder
+			last := 0
+			for _, i := range dollars {
+				expr.WriteString(value[last:i])
+				expr.WriteByte('\\')
+				last = i
+			}
+			expr.WriteString(value[last:])
+			return value, expr.String(), nil
+		case c == '\r' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n':
+			// CRLF line endings are read as LF
+		case c == '\n':
+			b.WriteByte(c)
+			p.line++
+			p.lineStart = p.pos + 1
+		case c == '\\' && q == '"' && p.pos+1 < len(p.src):
+			p.pos++
+			switch c := p.src[p.pos]; c {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(c)
+			case '$':
+				// the dollar is literal, the expansion reads it escaped
+				dollars = append(dollars, b.Len())
+				b.WriteByte(c)
+			default:
+				// unknown sequences are kept
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.line, p.lineStart, p.pos = line, lineStart, open
+	return "", "", p.errorf("unterminated quoted value")
+}
+
+func (p *dotenvParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// atEOL reports whether the position is at the end of a line, or of the input.
+func (p *dotenvParser) atEOL() bool {
+	rest := p.src[p.pos:]
+	return rest == "" || rest[0] == '\n' || rest == "\r" || strings.HasPrefix(rest, "\r\n")
+}
+
+// skipLine moves to the beginning of the next line.
+func (p *dotenvParser) skipLine() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+	if p.pos < len(p.src) {
+		p.newline()
+	}
+}
+
+func (p *dotenvParser) newline() {
+	p.pos++
+	p.line++
+	p.lineStart = p.pos
+}
+
+func (p *dotenvParser) char() string {
+	r := []rune(p.src[p.pos:])
+	if len(r) == 0 {
+		return ""
+	}
+	return string(r[0])
+}
+
+// lineAt returns the content of the given line.
+func (p *dotenvParser) lineAt(line int) string {
+	lines := strings.SplitN(p.src, "\n", line+1)
+	if line > len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line-1], "\r")
+}
+
+func (p *dotenvParser) errorf(format string, args ...any) error {
+	return &ParseError{
+		Filename: p.opts.Filename,
+		Line:     p.line,
+		Column:   len([]rune(p.src[p.lineStart:p.pos])) + 1,
+		Snippet:  p.lineAt(p.line),
+		Msg:      fmt.Sprintf(format, args...),
+	}
+}
+
+// checkDotenvKey checks that the key can be written in a dotenv file.
+func checkDotenvKey(key string) error {
+	if key == "" || strings.IndexFunc(key, func(r rune) bool { return r > 0x7f || !isKeyChar(byte(r)) }) >= 0 {
+		return errors.New("env: invalid key " + strconv.Quote(key))
+	}
+	return nil
+}
+
+func isKeyChar(c byte) bool {
+	return isAlphaNum(c) || c == '.' || c == '-'
+}
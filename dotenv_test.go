@@ -0,0 +1,112 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("conformance", func(t *testing.T) {
+		files, err := filepath.Glob("testdata/dotenv/*.env")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, files)
+		for _, file := range files {
+			t.Run(filepath.Base(file), func(t *testing.T) {
+				f, err := os.Open(file)
+				assert.NoError(t, err)
+				defer f.Close()
+				vars, err := Parse(f, ParseOptions{})
+				assert.NoError(t, err)
+				data, err := os.ReadFile(strings.TrimSuffix(file, ".env") + ".json")
+				assert.NoError(t, err)
+				var expected map[string]string
+				assert.NoError(t, json.Unmarshal(data, &expected))
+				assert.Equal(t, expected, vars)
+			})
+		}
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		entries, err := parseEntries(strings.NewReader(`# comment
+export A=1
+B = two words # comment
+C="line1\nline2 \$HOME"
+D='${A}'
+E="multi
+line"
+
+F=
+`), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []entry{
+			{key: "A", value: "1", line: 2},
+			{key: "B", value: "two words", line: 3},
+			{key: "C", value: "line1\nline2 $HOME", expr: "line1\nline2 \\$HOME", line: 4},
+			{key: "D", value: "${A}", literal: true, line: 5},
+			{key: "E", value: "multi\nline", line: 6},
+			{key: "F", value: "", line: 9},
+		}, entries)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			src, err string
+		}{
+			{"A=1\nB\n", `env: 2:2: missing '=' after key B: "B"`},
+			{"A=1\n\nB C=2\n", `env: 3:3: invalid character "C" in key: "B C=2"`},
+			{"A=1\n=2\n", `env: 2:1: invalid character "=" in key: "=2"`},
+			{"A=1\nB=\"x\ny\n", `env: 2:3: unterminated quoted value: "B=\"x"`},
+			{"A='1'2\n", `env: 1:6: unexpected character "2" after quoted value: "A='1'2"`},
+			{"é=1\n", `env: 1:1: invalid character "é" in key: "é=1"`},
+		}
+		for _, c := range cases {
+			_, err := Parse(strings.NewReader(c.src), ParseOptions{})
+			assert.EqualError(t, err, c.err, c.src)
+		}
+
+		_, err := Parse(strings.NewReader("A=1\r\nB\r\n"), ParseOptions{Filename: ".env"})
+		var parseErr *ParseError
+		assert.True(t, errors.As(err, &parseErr))
+		assert.Equal(t, &ParseError{Filename: ".env", Line: 2, Column: 2, Snippet: "B", Msg: "missing '=' after key B"}, parseErr)
+		assert.EqualError(t, err, `env: .env:2:2: missing '=' after key B: "B"`)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		src := "A=1\nB=2\nA=3\n"
+		entries, err := parseEntries(strings.NewReader(src), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []entry{{key: "A", value: "3", line: 3}, {key: "B", value: "2", line: 2}}, entries)
+
+		var warnings []error
+		vars, err := Parse(strings.NewReader(src), ParseOptions{Duplicates: DuplicateWarn, Warn: func(err error) {
+			warnings = append(warnings, err)
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"A": "3", "B": "2"}, vars)
+		if assert.Len(t, warnings, 1) {
+			assert.EqualError(t, warnings[0], `env: 3:1: duplicate key A, first defined on line 1: "A=3"`)
+		}
+
+		_, err = Parse(strings.NewReader(src), ParseOptions{Filename: ".env", Duplicates: DuplicateError})
+		assert.EqualError(t, err, `env: .env:3:1: duplicate key A, first defined on line 1: "A=3"`)
+	})
+
+	t.Run("load", func(t *testing.T) {
+		dir := t.TempDir()
+		filename := filepath.Join(dir, ".env")
+		assert.NoError(t, os.WriteFile(filename, []byte("A=1\nA=2\n"), 0o600))
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{}, filename))
+		assert.Equal(t, "2", e.Get("A"))
+		err := New().LoadWithOptions(LoadOptions{Duplicates: DuplicateError}, filename)
+		var parseErr *ParseError
+		assert.True(t, errors.As(err, &parseErr))
+		assert.Equal(t, filename, parseErr.Filename)
+	})
+}
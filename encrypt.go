@@ -0,0 +1,407 @@
+package env
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// KeyEnv is the name of the variable which holds the key of the encrypted files when no key is given.
+// The key may also be read from the file named by the variable suffixed with _FILE, such as ENV_KEY_FILE.
+var KeyEnv = "ENV_KEY"
+
+// Key is a key encrypting and decrypting dotenv files and values.
+//
+// It is either a symmetric AES-256 key, or an X25519 key pair, age-style:
+// files are encrypted with the public key and decrypted with the private key,
+// so that anyone can encrypt values while only the holders of the private key can decrypt them.
+//
+// The encrypted values are written ENC[algorithm,data] where data is encoded in base64,
+// and an encrypted file holds a single encrypted value. The values of the variables are bound to their names,
+// so that they cannot be moved to another variable of a file. The zero Key is invalid.
+type Key struct {
+	secret  []byte
+	private *ecdh.PrivateKey
+	public  *ecdh.PublicKey
+}
+
+const (
+	aesKeyPrefix       = "aes256:"
+	x25519KeyPrefix    = "x25519:"
+	x25519PublicPrefix = "x25519-public:"
+	encPrefix          = "ENC["
+)
+
+// GenerateKey returns a new random AES-256 key.
+func GenerateKey() (*Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &Key{secret: secret}, nil
+}
+
+// GenerateKeyPair returns a new random X25519 private key, see [Key.Public] for its public key.
+func GenerateKeyPair() (*Key, error) {
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{private: private, public: private.PublicKey()}, nil
+}
+
+// ParseKey parses a key formatted by [Key.String].
+func ParseKey(s string) (*Key, error) {
+	s = strings.TrimSpace(s)
+	var prefix string
+	for _, p := range []string{aesKeyPrefix, x25519PublicPrefix, x25519KeyPrefix} {
+		if strings.HasPrefix(s, p) {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" {
+		return nil, errors.New("env: unknown key format")
+	}
+	b, err := base64.StdEncoding.DecodeString(s[len(prefix):])
+	if err != nil {
+		return nil, fmt.Errorf("env: invalid key: %w", err)
+	}
+	switch prefix {
+	case aesKeyPrefix:
+		if len(b) != 32 {
+			return nil, errors.New("env: invalid key: AES-256 keys are 32 bytes long")
+		}
+		return &Key{secret: b}, nil
+	case x25519KeyPrefix:
+		private, err := ecdh.X25519().NewPrivateKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("env: invalid key: %w", err)
+		}
+		return &Key{private: private, public: private.PublicKey()}, nil
+	default:
+		public, err := ecdh.X25519().NewPublicKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("env: invalid key: %w", err)
+		}
+		return &Key{public: public}, nil
+	}
+}
+
+// ReadKeyFile reads the key of the named file, see [ParseKey].
+func ReadKeyFile(filename string) (*Key, error) {
+	s, err := readSecretFile(filename, 4<<10)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(s)
+}
+
+// errInvalidKey is returned when encrypting or decrypting with a nil or zero Key.
+var errInvalidKey = errors.New("env: invalid key")
+
+// valid reports whether the key is a key returned by the functions of the package, not a nil or zero Key.
+func (k *Key) valid() bool {
+	return k != nil && (k.secret != nil || k.public != nil)
+}
+
+// String formats the key: aes256:, x25519: or x25519-public: followed by the key encoded in base64.
+// It returns an empty string for a nil or zero Key.
+func (k *Key) String() string {
+	switch {
+	case !k.valid():
+		return ""
+	case k.secret != nil:
+		return aesKeyPrefix + base64.StdEncoding.EncodeToString(k.secret)
+	case k.private != nil:
+		return x25519KeyPrefix + base64.StdEncoding.EncodeToString(k.private.Bytes())
+	default:
+		return x25519PublicPrefix + base64.StdEncoding.EncodeToString(k.public.Bytes())
+	}
+}
+
+// Public returns the public key of an X25519 key pair, which only encrypts, or the key itself for an AES key
+// or a nil or zero Key.
+func (k *Key) Public() *Key {
+	if !k.valid() || k.secret != nil {
+		return k
+	}
+	return &Key{public: k.public}
+}
+
+// EncryptValue encrypts the value of the named variable into an ENC[...] value,
+// which only decrypts for the same name, see [DecryptValue]. A whole file is encrypted with an empty name.
+func EncryptValue(name, value string, key *Key) (string, error) {
+	if !key.valid() {
+		return "", errInvalidKey
+	}
+	var algorithm string
+	var data []byte
+	var err error
+	if key.secret != nil {
+		algorithm = "aes256gcm"
+		data, err = sealAES(key.secret, []byte(value), []byte(name))
+	} else {
+		algorithm = "x25519"
+		data, err = key.sealX25519([]byte(value), []byte(name))
+	}
+	if err != nil {
+		return "", err
+	}
+	return encPrefix + algorithm + "," + base64.StdEncoding.EncodeToString(data) + "]", nil
+}
+
+// DecryptValue decrypts the ENC[...] value of the variable of the given name, see [EncryptValue].
+func DecryptValue(name, value string, key *Key) (string, error) {
+	if !key.valid() {
+		return "", errInvalidKey
+	}
+	if !isEncrypted(value) {
+		return "", errors.New("env: value is not encrypted")
+	}
+	algorithm, encoded, _ := strings.Cut(value[len(encPrefix):len(value)-1], ",")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("env: invalid encrypted value: %w", err)
+	}
+	var plain []byte
+	switch algorithm {
+	case "aes256gcm":
+		if key.secret == nil {
+			return "", errors.New("env: value encrypted with an AES key")
+		}
+		plain, err = openAES(key.secret, data, []byte(name))
+	case "x25519":
+		if key.private == nil {
+			return "", errors.New("env: value encrypted with an X25519 key, the private key is required")
+		}
+		plain, err = key.openX25519(data, []byte(name))
+	default:
+		return "", fmt.Errorf("env: unknown encryption algorithm %q", algorithm)
+	}
+	if err != nil {
+		return "", fmt.Errorf("env: cannot decrypt value: %w", err)
+	}
+	return string(plain), nil
+}
+
+// Encrypt encrypts a whole dotenv file.
+func Encrypt(data []byte, key *Key) ([]byte, error) {
+	value, err := EncryptValue("", string(data), key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value + "\n"), nil
+}
+
+// EncryptValues encrypts the values of the named keys of a dotenv file, or all its values if no key is named.
+// The file keeps its formatting, see [Document], and the values which are already encrypted are kept as they are.
+func EncryptValues(data []byte, key *Key, keys ...string) ([]byte, error) {
+	return editValues(data, func(k, value string) (string, error) {
+		if isEncrypted(value) || len(keys) > 0 && !slices.Contains(keys, k) {
+			return value, nil
+		}
+		return EncryptValue(k, value, key)
+	})
+}
+
+// Decrypt decrypts an encrypted dotenv file, or the encrypted values of a dotenv file.
+func Decrypt(data []byte, key *Key) ([]byte, error) {
+	if isEncryptedFile(data) {
+		value, err := DecryptValue("", string(bytes.TrimSpace(data)), key)
+		return []byte(value), err
+	}
+	return editValues(data, func(k, value string) (string, error) {
+		if !isEncrypted(value) {
+			return value, nil
+		}
+		return DecryptValue(k, value, key)
+	})
+}
+
+// Rotate decrypts an encrypted dotenv file, or the encrypted values of a dotenv file, with the old key,
+// and encrypts them again with the new key.
+func Rotate(data []byte, oldKey, newKey *Key) ([]byte, error) {
+	if isEncryptedFile(data) {
+		plain, err := Decrypt(data, oldKey)
+		if err != nil {
+			return nil, err
+		}
+		return Encrypt(plain, newKey)
+	}
+	return editValues(data, func(k, value string) (string, error) {
+		if !isEncrypted(value) {
+			return value, nil
+		}
+		plain, err := DecryptValue(k, value, oldKey)
+		if err != nil {
+			return "", err
+		}
+		return EncryptValue(k, plain, newKey)
+	})
+}
+
+// editValues replaces the values of a dotenv file by the edited ones.
+func editValues(data []byte, edit func(key, value string) (string, error)) ([]byte, error) {
+	d, err := ParseDocument(bytes.NewReader(data), ParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range d.lines {
+		if !l.entry {
+			continue
+		}
+		value, err := edit(l.key, l.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w (key %s)", err, l.key)
+		}
+		if value != l.value {
+			l.setValue(value)
+		}
+	}
+	return []byte(d.String()), nil
+}
+
+// isEncrypted reports whether the value is an ENC[...] value.
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, "]")
+}
+
+// isEncryptedFile reports whether the content of a file is encrypted as a whole.
+// A dotenv file cannot start with ENC[ since [ is not valid in a key.
+func isEncryptedFile(data []byte) bool {
+	return isEncrypted(string(bytes.TrimSpace(data)))
+}
+
+// decryptionKey returns the key decrypting the files loaded as configured by opts.
+func (e *Env) decryptionKey(opts LoadOptions) (*Key, error) {
+	if opts.Key != nil {
+		return opts.Key, nil
+	}
+	if opts.KeyFile != "" {
+		return ReadKeyFile(opts.KeyFile)
+	}
+	if s, ok := e.lookup(KeyEnv); ok {
+		return ParseKey(s)
+	}
+	if filename, ok := e.lookup(KeyEnv + "_FILE"); ok {
+		return ReadKeyFile(filename)
+	}
+	return nil, errors.New("env: encrypted file without key, set " + KeyEnv)
+}
+
+// decryptionKey returns the key decrypting the loaded files.
+func (opts LoadOptions) decryptionKey() (*Key, error) {
+	if opts.lookupKey != nil {
+		return opts.lookupKey()
+	}
+	if opts.Key != nil {
+		return opts.Key, nil
+	}
+	return nil, errors.New("env: encrypted file without key")
+}
+
+// decryptEntries decrypts the encrypted values of the entries, which are not expanded then.
+func decryptEntries(entries []entry, key func() (*Key, error)) error {
+	for i, ent := range entries {
+		if !isEncrypted(ent.value) {
+			continue
+		}
+		k, err := key()
+		if err != nil {
+			return err
+		}
+		value, err := DecryptValue(ent.key, ent.value, k)
+		if err != nil {
+			return fmt.Errorf("%w (key %s)", err, ent.key)
+		}
+		entries[i].value, entries[i].literal = value, true
+	}
+	return nil
+}
+
+// sealAES encrypts plain with AES-256-GCM, and returns the nonce followed by the ciphertext.
+func sealAES(key, plain, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, additionalData), nil
+}
+
+// openAES decrypts data sealed by sealAES.
+func openAES(key, data, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealX25519 encrypts plain for the public key with an ephemeral key,
+// and returns the ephemeral public key followed by the sealed data.
+func (k *Key) sealX25519(plain, additionalData []byte) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(k.public)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealAES(deriveKey(shared, ephemeral.PublicKey().Bytes(), k.public.Bytes()), plain, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeral.PublicKey().Bytes(), sealed...), nil
+}
+
+// openX25519 decrypts data sealed by sealX25519.
+func (k *Key) openX25519(data, additionalData []byte) ([]byte, error) {
+	if len(data) < 32 {
+		return nil, errors.New("ciphertext too short")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(data[:32])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := k.private.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	return openAES(deriveKey(shared, data[:32], k.public.Bytes()), data[32:], additionalData)
+}
+
+// deriveKey derives an AES-256 key from an X25519 shared secret with HKDF-SHA256,
+// salted with the ephemeral and recipient public keys.
+func deriveKey(shared, ephemeral, recipient []byte) []byte {
+	extract := hmac.New(sha256.New, append(append([]byte{}, ephemeral...), recipient...))
+	extract.Write(shared)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte("env x25519\x01"))
+	return expand.Sum(nil)
+}
@@ -0,0 +1,150 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncrypt(t *testing.T) {
+	aesKey, err := GenerateKey()
+	assert.NoError(t, err)
+	pair, err := GenerateKeyPair()
+	assert.NoError(t, err)
+
+	t.Run("keys", func(t *testing.T) {
+		for _, key := range []*Key{aesKey, pair, pair.Public()} {
+			parsed, err := ParseKey(key.String())
+			assert.NoError(t, err)
+			assert.Equal(t, key.String(), parsed.String())
+		}
+		assert.True(t, strings.HasPrefix(pair.Public().String(), "x25519-public:"))
+		_, err := ParseKey("aes256:AAAA")
+		assert.EqualError(t, err, "env: invalid key: AES-256 keys are 32 bytes long")
+		_, err = ParseKey("rsa:AAAA")
+		assert.EqualError(t, err, "env: unknown key format")
+	})
+
+	t.Run("values", func(t *testing.T) {
+		for _, key := range []*Key{aesKey, pair} {
+			enc, err := EncryptValue("DB_PASSWORD", "s3cr3t $HOME", key.Public())
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(enc, "ENC["))
+			plain, err := DecryptValue("DB_PASSWORD", enc, key)
+			assert.NoError(t, err)
+			assert.Equal(t, "s3cr3t $HOME", plain)
+			_, err = DecryptValue("API_TOKEN", enc, key)
+			assert.ErrorContains(t, err, "env: cannot decrypt value")
+		}
+		enc, err := EncryptValue("DB_PASSWORD", "s3cr3t", pair.Public())
+		assert.NoError(t, err)
+		_, err = DecryptValue("DB_PASSWORD", enc, pair.Public())
+		assert.EqualError(t, err, "env: value encrypted with an X25519 key, the private key is required")
+		other, err := GenerateKeyPair()
+		assert.NoError(t, err)
+		_, err = DecryptValue("DB_PASSWORD", enc, other)
+		assert.ErrorContains(t, err, "env: cannot decrypt value")
+		_, err = DecryptValue("DB_PASSWORD", "plain", aesKey)
+		assert.EqualError(t, err, "env: value is not encrypted")
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []*Key{nil, {}} {
+			_, err := EncryptValue("DB_PASSWORD", "s3cr3t", key)
+			assert.EqualError(t, err, "env: invalid key")
+			_, err = DecryptValue("DB_PASSWORD", "ENC[aes256gcm,AAAA]", key)
+			assert.EqualError(t, err, "env: invalid key")
+			_, err = Encrypt([]byte("A=1\n"), key)
+			assert.EqualError(t, err, "env: invalid key")
+			assert.Equal(t, "", key.String())
+			assert.Equal(t, key, key.Public())
+		}
+	})
+
+	src := []byte("# database\nDB_USER=root\nDB_PASSWORD='s3cr3t' # the password\n")
+
+	t.Run("files", func(t *testing.T) {
+		enc, err := Encrypt(src, aesKey)
+		assert.NoError(t, err)
+		plain, err := Decrypt(enc, aesKey)
+		assert.NoError(t, err)
+		assert.Equal(t, src, plain)
+
+		enc, err = EncryptValues(src, pair.Public(), "DB_PASSWORD")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(enc), "# database\nDB_USER=root\nDB_PASSWORD='ENC[x25519,"))
+		assert.True(t, strings.HasSuffix(string(enc), "]' # the password\n"))
+		plain, err = Decrypt(enc, pair)
+		assert.NoError(t, err)
+		assert.Equal(t, src, plain)
+
+		enc, err = EncryptValues([]byte("A=1\nB=2\n"), aesKey)
+		assert.NoError(t, err)
+		a, b, _ := strings.Cut(strings.TrimSuffix(string(enc), "\n"), "\n")
+		swapped := "A=" + strings.TrimPrefix(b, "B=") + "\nB=" + strings.TrimPrefix(a, "A=") + "\n"
+		_, err = Decrypt([]byte(swapped), aesKey)
+		assert.ErrorContains(t, err, "(key A)")
+		err = New().LoadFSWithOptions(fstest.MapFS{".env": {Data: []byte(swapped)}}, LoadOptions{Key: aesKey})
+		assert.ErrorContains(t, err, "env: cannot decrypt value")
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		newKey, err := GenerateKey()
+		assert.NoError(t, err)
+		enc, err := EncryptValues(src, aesKey)
+		assert.NoError(t, err)
+		rotated, err := Rotate(enc, aesKey, newKey)
+		assert.NoError(t, err)
+		_, err = Decrypt(rotated, aesKey)
+		assert.Error(t, err)
+		plain, err := Decrypt(rotated, newKey)
+		assert.NoError(t, err)
+		assert.Equal(t, src, plain)
+
+		enc, err = Encrypt(src, aesKey)
+		assert.NoError(t, err)
+		rotated, err = Rotate(enc, aesKey, pair)
+		assert.NoError(t, err)
+		plain, err = Decrypt(rotated, pair)
+		assert.NoError(t, err)
+		assert.Equal(t, src, plain)
+	})
+
+	t.Run("load", func(t *testing.T) {
+		dir := t.TempDir()
+		whole := filepath.Join(dir, "whole.env")
+		values := filepath.Join(dir, "values.env")
+		keyFile := filepath.Join(dir, "key")
+		enc, err := Encrypt([]byte("A=1\nB=${A}2\n"), aesKey)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(whole, enc, 0o600))
+		enc, err = EncryptValues([]byte("C=$HOME\nD=${A}\n"), aesKey, "C")
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(values, enc, 0o600))
+		assert.NoError(t, os.WriteFile(keyFile, []byte(aesKey.String()+"\n"), 0o600))
+
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Expand: true, Key: aesKey}, whole, values))
+		assert.Equal(t, map[string]string{"A": "1", "B": "12", "C": "$HOME", "D": "1"}, e.Map())
+
+		e = FromMap(map[string]string{"ENV_KEY": aesKey.String()})
+		assert.NoError(t, e.Load(whole, values))
+		assert.Equal(t, "$HOME", e.Get("C"))
+
+		e = FromMap(map[string]string{"ENV_KEY_FILE": keyFile})
+		assert.NoError(t, e.Load(whole))
+		assert.Equal(t, "12", e.Get("B"))
+
+		e = New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{KeyFile: keyFile}, values))
+		assert.Equal(t, "$HOME", e.Get("C"))
+
+		err = New().Load(values)
+		assert.EqualError(t, err, "env: encrypted file without key, set ENV_KEY (file "+values+")")
+		assert.NoError(t, New().Load(filepath.Join("testdata", "load", ".env")))
+	})
+}
@@ -3,9 +3,20 @@ package env
 import (
 	"github.com/stretchr/testify/assert"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 )
 
+func TestFromReader(t *testing.T) {
+	vars, err := FromReader(strings.NewReader("B=${A}/x\nA=1\nC='${A}'\nD=\"\\$A\"\nE=$MISSING\nH=#hash\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "1/x", "C": "${A}", "D": "$A", "E": "", "H": "#hash"}, vars)
+
+	_, err = FromReader(strings.NewReader("A=${B}\nB=${A}\n"))
+	assert.EqualError(t, err, "env: reference cycle A -> B -> A")
+}
+
 func TestExpand(t *testing.T) {
 	t.Run("without variable", func(t *testing.T) {
 		value := Expand("test_value")
@@ -197,6 +208,37 @@ func TestGetT(t *testing.T) {
 	})
 }
 
+func TestLookupT(t *testing.T) {
+	t.Run("int", func(t *testing.T) {
+		_ = os.Setenv("TEST_ENV_KEY", "123")
+		defer func() {
+			_ = os.Unsetenv("TEST_ENV_KEY")
+		}()
+		value, ok, err := LookupInt("TEST_ENV_KEY")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 123, value)
+		_ = os.Unsetenv("TEST_ENV_KEY")
+		_, ok, err = LookupInt("TEST_ENV_KEY")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("empty", func(t *testing.T) {
+		_ = os.Setenv("TEST_ENV_KEY", "")
+		defer func() {
+			_ = os.Unsetenv("TEST_ENV_KEY")
+			TreatEmptyAsUnset(false)
+		}()
+		_, ok, err := LookupT("TEST_ENV_KEY", strconv.Atoi)
+		assert.Error(t, err)
+		assert.True(t, ok)
+		TreatEmptyAsUnset(true)
+		_, ok, err = LookupT("TEST_ENV_KEY", strconv.Atoi)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
 func TestGetOr(t *testing.T) {
 	t.Run("string", func(t *testing.T) {
 		_ = os.Setenv("TEST_ENV_KEY", "test_value")
@@ -0,0 +1,501 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/caarlos0/env/v11"
+)
+
+// std is the default instance used by the package-level functions.
+// It reads from and writes to the process environment.
+var std = &Env{system: true}
+
+// Env is a set of environment variables.
+//
+// An Env either operates directly on the process environment (see [Default]),
+// holds its variables in memory (see [New]), or keeps its own variables in memory
+// while falling back to a [Source] for lookups (see [NewOverlay] and [NewFrom]).
+// The zero value is an empty Env isolated from the process environment, like the one returned by New.
+// An Env is a [Source] itself and is safe for concurrent use.
+type Env struct {
+	mu     sync.RWMutex
+	vars   map[string]string
+	unset  map[string]bool
+	system bool
+	parent Source
+	// secrets configures the resolution of the KEY_FILE variables, which are not resolved when it is nil.
+	secrets *FileSecretOptions
+	// resolvers resolves the values which are references to secrets, which are not resolved when it is nil.
+	resolvers *Resolvers
+	// emptyUnset reports whether the lookups treat the empty values as unset.
+	emptyUnset bool
+}
+
+// New returns an empty Env which is isolated from the process environment.
+func New() *Env {
+	return &Env{
+		vars:  make(map[string]string),
+		unset: make(map[string]bool),
+	}
+}
+
+// NewOverlay returns an empty Env layered over the process environment.
+// Writes are kept in memory and never reach the process environment,
+// lookups fall back to the process environment when the key is not set in memory.
+func NewOverlay() *Env {
+	return NewFrom(OSSource{})
+}
+
+// NewFrom returns an empty Env layered over the given source.
+// Writes are kept in memory, lookups fall back to the source when the key is not set in memory.
+func NewFrom(src Source) *Env {
+	e := New()
+	e.parent = src
+	return e
+}
+
+// FromMap returns an isolated Env initialized with a copy of the given variables.
+func FromMap(vars map[string]string) *Env {
+	e := New()
+	for k, v := range vars {
+		e.vars[k] = v
+	}
+	return e
+}
+
+// Default returns the Env used by the package-level functions.
+func Default() *Env {
+	return std
+}
+
+// TreatEmptyAsUnset makes the lookups of the Env report the variables with an empty value as not present
+// when enabled, see [Env.Lookup]. It tells an explicitly disabled variable apart from a variable which is not configured
+// when it is disabled, the default. Keys and Map still hold the empty variables.
+func (e *Env) TreatEmptyAsUnset(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.emptyUnset = enabled
+}
+
+// Lookup returns the value of the variable named by the key and whether it is present.
+//
+// Every read of the Env goes through its lookups: Get, the GetT and LookupT families of functions,
+// Unmarshal and the expansion see what Lookup sees, including the empty variables reported as not present
+// (see [Env.TreatEmptyAsUnset]), the secret files (see [Env.UseFileSecrets]) and the references
+// to secrets (see [Env.UseResolvers]). Lookup and Get report a variable which cannot be resolved
+// as not present, while GetT and Unmarshal return the error.
+func (e *Env) Lookup(key string) (string, bool) {
+	value, ok, err := e.lookupErr(key)
+	if err != nil {
+		return "", false
+	}
+	return value, ok
+}
+
+// lookup returns the value of the variable named by the key, without resolving the secret files.
+func (e *Env) lookup(key string) (string, bool) {
+	if e.system {
+		return os.LookupEnv(key)
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if value, ok := e.vars[key]; ok {
+		return value, true
+	}
+	if e.parent != nil && !e.unset[key] {
+		return e.parent.Lookup(key)
+	}
+	return "", false
+}
+
+// Set sets the value of the variable named by the key.
+func (e *Env) Set(key string, value string) error {
+	if e.system {
+		return os.Setenv(key, value)
+	}
+	if key == "" || strings.ContainsAny(key, "=\x00") {
+		return errors.New("env: invalid key " + strconv.Quote(key))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.vars == nil {
+		e.vars = make(map[string]string)
+	}
+	e.vars[key] = value
+	delete(e.unset, key)
+	return nil
+}
+
+// Unset removes the variable named by the key.
+// For a layered Env, the variable is also hidden from the underlying source.
+func (e *Env) Unset(key string) error {
+	if e.system {
+		return os.Unsetenv(key)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.vars, key)
+	if e.parent != nil {
+		if e.unset == nil {
+			e.unset = make(map[string]bool)
+		}
+		e.unset[key] = true
+	}
+	return nil
+}
+
+// Keys returns the names of all the variables present in the Env.
+// When the secret files are enabled, the names of the variables resolved from a KEY_FILE variable are included.
+func (e *Env) Keys() []string {
+	vars := e.Map()
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	if opts := e.fileSecrets(); opts != nil {
+		for k := range vars {
+			if key, ok := strings.CutSuffix(k, opts.suffix()); ok && key != "" {
+				if _, ok := vars[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// Map returns a copy of all the variables present in the Env.
+func (e *Env) Map() map[string]string {
+	if e.system {
+		return ToMap(OSSource{})
+	}
+	vars := make(map[string]string)
+	if e.parent != nil {
+		vars = ToMap(e.parent)
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for k := range e.unset {
+		delete(vars, k)
+	}
+	for k, v := range e.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// Expand expands the variables in the given string using the values of the Env.
+// See [Expand] for the supported syntax.
+func (e *Env) Expand(s string) string {
+	value, _ := e.ExpandWithOptions(s, ExpandOptions{})
+	return value
+}
+
+// ExpandStrict is like Expand but fails when a referenced variable without default value is not set.
+// See [ExpandStrict].
+func (e *Env) ExpandStrict(s string) (string, error) {
+	return e.ExpandWithOptions(s, ExpandOptions{Strict: true})
+}
+
+// ExpandWithOptions expands the variables in the given string using the values of the Env, as configured by opts.
+// The ${ENV_KEY:=default} references assign their default value to the Env.
+// See [ExpandWithOptions].
+func (e *Env) ExpandWithOptions(s string, opts ExpandOptions) (string, error) {
+	return expandString(s, e.Lookup, e.Set, opts)
+}
+
+// SetInt is a shorthand for Set(key, strconv.Itoa(value)).
+func (e *Env) SetInt(key string, value int) error {
+	return e.Set(key, strconv.Itoa(value))
+}
+
+// SetInt64 is a shorthand for Set(key, strconv.FormatInt(value, 10)).
+func (e *Env) SetInt64(key string, value int64) error {
+	return e.Set(key, strconv.FormatInt(value, 10))
+}
+
+// SetUint64 is a shorthand for Set(key, strconv.FormatUint(value, 10)).
+func (e *Env) SetUint64(key string, value uint64) error {
+	return e.Set(key, strconv.FormatUint(value, 10))
+}
+
+// SetFloat64 is a shorthand for Set(key, strconv.FormatFloat(value, 'f', -1, 64)).
+func (e *Env) SetFloat64(key string, value float64) error {
+	return e.Set(key, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// SetBool is a shorthand for Set(key, strconv.FormatBool(value)).
+func (e *Env) SetBool(key string, value bool) error {
+	return e.Set(key, strconv.FormatBool(value))
+}
+
+// SetStrings is a shorthand for Set(key, strings.Join(value, ",")).
+func (e *Env) SetStrings(key string, value []string) error {
+	return e.Set(key, strings.Join(value, ","))
+}
+
+// SetJSON is a shorthand for Set(key, json.Marshal(value)).
+func (e *Env) SetJSON(key string, value any) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return e.Set(key, string(b))
+}
+
+// Get returns the value of the variable named by the key.
+func (e *Env) Get(key string) string {
+	value, _ := e.Lookup(key)
+	return value
+}
+
+// GetOr returns the value of the variable named by the key.
+// If the variable is not present, it returns the default value.
+func (e *Env) GetOr(key, defaultValue string) string {
+	if value, ok := e.Lookup(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetInt is a shorthand for GetTFrom[int](e, key, strconv.Atoi)
+func (e *Env) GetInt(key string) (int, error) {
+	return GetTFrom(e, key, strconv.Atoi)
+}
+
+// MustGetInt is a shorthand for MustGetTFrom[int](e, key, strconv.Atoi)
+func (e *Env) MustGetInt(key string) int {
+	return MustGetTFrom(e, key, strconv.Atoi)
+}
+
+// GetIntOr is a shorthand for GetTOrFrom[int](e, key, strconv.Atoi, defaultValue)
+func (e *Env) GetIntOr(key string, defaultValue int) (int, error) {
+	return GetTOrFrom(e, key, strconv.Atoi, defaultValue)
+}
+
+// GetInt64 is a shorthand for GetTFrom[int64](e, key, strconv.ParseInt)
+func (e *Env) GetInt64(key string) (int64, error) {
+	return GetTFrom(e, key, parseInt64)
+}
+
+// MustGetInt64 is a shorthand for MustGetTFrom[int64](e, key, strconv.ParseInt)
+func (e *Env) MustGetInt64(key string) int64 {
+	return MustGetTFrom(e, key, parseInt64)
+}
+
+// GetInt64Or is a shorthand for GetTOrFrom[int64](e, key, strconv.ParseInt, defaultValue)
+func (e *Env) GetInt64Or(key string, defaultValue int64) (int64, error) {
+	return GetTOrFrom(e, key, parseInt64, defaultValue)
+}
+
+// GetUint64 is a shorthand for GetTFrom[uint64](e, key, strconv.ParseUint)
+func (e *Env) GetUint64(key string) (uint64, error) {
+	return GetTFrom(e, key, parseUint64)
+}
+
+// MustGetUint64 is a shorthand for MustGetTFrom[uint64](e, key, strconv.ParseUint)
+func (e *Env) MustGetUint64(key string) uint64 {
+	return MustGetTFrom(e, key, parseUint64)
+}
+
+// GetUint64Or is a shorthand for GetTOrFrom[uint64](e, key, strconv.ParseUint, defaultValue)
+func (e *Env) GetUint64Or(key string, defaultValue uint64) (uint64, error) {
+	return GetTOrFrom(e, key, parseUint64, defaultValue)
+}
+
+// GetFloat64 is a shorthand for GetTFrom[float64](e, key, strconv.ParseFloat)
+func (e *Env) GetFloat64(key string) (float64, error) {
+	return GetTFrom(e, key, parseFloat64)
+}
+
+// MustGetFloat64 is a shorthand for MustGetTFrom[float64](e, key, strconv.ParseFloat)
+func (e *Env) MustGetFloat64(key string) float64 {
+	return MustGetTFrom(e, key, parseFloat64)
+}
+
+// GetFloat64Or is a shorthand for GetTOrFrom[float64](e, key, strconv.ParseFloat, defaultValue)
+func (e *Env) GetFloat64Or(key string, defaultValue float64) (float64, error) {
+	return GetTOrFrom(e, key, parseFloat64, defaultValue)
+}
+
+// GetBool is a shorthand for GetTFrom[bool](e, key, strconv.ParseBool)
+func (e *Env) GetBool(key string) (bool, error) {
+	return GetTFrom(e, key, strconv.ParseBool)
+}
+
+// MustGetBool is a shorthand for MustGetTFrom[bool](e, key, strconv.ParseBool)
+func (e *Env) MustGetBool(key string) bool {
+	return MustGetTFrom(e, key, strconv.ParseBool)
+}
+
+// GetBoolOr is a shorthand for GetTOrFrom[bool](e, key, strconv.ParseBool, defaultValue)
+func (e *Env) GetBoolOr(key string, defaultValue bool) (bool, error) {
+	return GetTOrFrom(e, key, strconv.ParseBool, defaultValue)
+}
+
+// GetStrings is a shorthand for GetTFrom[[]string]
+func (e *Env) GetStrings(key string) ([]string, error) {
+	return GetTFrom(e, key, splitStrings)
+}
+
+// MustGetStrings is a shorthand for MustGetTFrom[[]string]
+func (e *Env) MustGetStrings(key string) []string {
+	return MustGetTFrom(e, key, splitStrings)
+}
+
+// GetStringsOr is a shorthand for GetTOrFrom[[]string](e, key, func(s string) ([]string, error), defaultValue)
+func (e *Env) GetStringsOr(key string, defaultValue []string) ([]string, error) {
+	return GetTOrFrom(e, key, splitStrings, defaultValue)
+}
+
+// LookupInt is a shorthand for LookupTFrom[int](e, key, strconv.Atoi)
+func (e *Env) LookupInt(key string) (int, bool, error) {
+	return LookupTFrom(e, key, strconv.Atoi)
+}
+
+// LookupInt64 is a shorthand for LookupTFrom[int64](e, key, strconv.ParseInt)
+func (e *Env) LookupInt64(key string) (int64, bool, error) {
+	return LookupTFrom(e, key, parseInt64)
+}
+
+// LookupUint64 is a shorthand for LookupTFrom[uint64](e, key, strconv.ParseUint)
+func (e *Env) LookupUint64(key string) (uint64, bool, error) {
+	return LookupTFrom(e, key, parseUint64)
+}
+
+// LookupFloat64 is a shorthand for LookupTFrom[float64](e, key, strconv.ParseFloat)
+func (e *Env) LookupFloat64(key string) (float64, bool, error) {
+	return LookupTFrom(e, key, parseFloat64)
+}
+
+// LookupBool is a shorthand for LookupTFrom[bool](e, key, strconv.ParseBool)
+func (e *Env) LookupBool(key string) (bool, bool, error) {
+	return LookupTFrom(e, key, strconv.ParseBool)
+}
+
+// LookupStrings is a shorthand for LookupTFrom[[]string]
+func (e *Env) LookupStrings(key string) ([]string, bool, error) {
+	return LookupTFrom(e, key, splitStrings)
+}
+
+// GetTFrom returns the value of the variable named by the key in the given source.
+// It converts the value to the specified type using the provided conversion function.
+// When the variable is not present, it converts the empty string, see [LookupTFrom] to tell them apart.
+func GetTFrom[T any](src Source, key string, convert func(s string) (T, error)) (T, error) {
+	value, _, err := lookupErr(src, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return convert(value)
+}
+
+// MustGetTFrom returns the value of the variable named by the key in the given source.
+// It converts the value to the specified type using the provided conversion function.
+// If the value cannot be converted, it panics.
+func MustGetTFrom[T any](src Source, key string, convert func(s string) (T, error)) T {
+	value, err := GetTFrom[T](src, key, convert)
+	if err == nil {
+		return value
+	}
+	panic(err)
+}
+
+// GetTOrFrom returns the value of the variable named by the key in the given source.
+// It converts the value to the specified type using the provided conversion function.
+// If the variable is not present, it returns the default value.
+// If the value cannot be converted, it returns the default value and an error.
+func GetTOrFrom[T any](src Source, key string, convert func(s string) (T, error), defaultValue T) (T, error) {
+	if value, ok, err := lookupErr(src, key); err != nil {
+		return defaultValue, err
+	} else if ok {
+		if value, err := convert(value); err != nil {
+			return defaultValue, err
+		} else {
+			return value, nil
+		}
+	} else {
+		return defaultValue, nil
+	}
+}
+
+// LookupTFrom returns the value of the variable named by the key in the given source, and whether it is present.
+// It converts the value to the specified type using the provided conversion function.
+// If the variable is not present, it returns the zero value, false and no error without calling the conversion function.
+// If the value cannot be converted, it returns the zero value, true and an error.
+func LookupTFrom[T any](src Source, key string, convert func(s string) (T, error)) (T, bool, error) {
+	var zero T
+	value, ok, err := lookupErr(src, key)
+	if err != nil || !ok {
+		return zero, false, err
+	}
+	converted, err := convert(value)
+	if err != nil {
+		return zero, true, err
+	}
+	return converted, true, nil
+}
+
+// GetJSONFrom is a shorthand for GetTFrom[T](e, key, json.Unmarshal)
+func GetJSONFrom[T any](src Source, key string) (T, error) {
+	return GetTFrom(src, key, unmarshalJSON[T])
+}
+
+// MustGetJSONFrom is a shorthand for MustGetTFrom[T](e, key, json.Unmarshal)
+func MustGetJSONFrom[T any](src Source, key string) T {
+	return MustGetTFrom(src, key, unmarshalJSON[T])
+}
+
+// LookupJSONFrom is a shorthand for LookupTFrom[T](e, key, json.Unmarshal)
+func LookupJSONFrom[T any](src Source, key string) (T, bool, error) {
+	return LookupTFrom(src, key, unmarshalJSON[T])
+}
+
+// GetJSONOrFrom is a shorthand for GetTOrFrom[T](e, key, json.Unmarshal, defaultValue)
+func GetJSONOrFrom[T any](src Source, key string, defaultValue T) (T, error) {
+	return GetTOrFrom(src, key, unmarshalJSON[T], defaultValue)
+}
+
+// UnmarshalFrom parses the variables of the given source into a value of type T.
+func UnmarshalFrom[T any](src Source) (T, error) {
+	vars, err := toMapErr(src)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return env.ParseAsWithOptions[T](env.Options{Environment: vars})
+}
+
+// MustUnmarshalFrom is like UnmarshalFrom but panics if the variables cannot be parsed.
+func MustUnmarshalFrom[T any](src Source) T {
+	return env.Must(UnmarshalFrom[T](src))
+}
+
+func parseInt64(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func parseUint64(s string) (uint64, error) {
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func parseFloat64(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func splitStrings(s string) ([]string, error) {
+	return strings.Split(s, ","), nil
+}
+
+func unmarshalJSON[T any](s string) (T, error) {
+	var value T
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return value, err
+	}
+	return value, nil
+}
@@ -0,0 +1,155 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnv(t *testing.T) {
+	t.Run("isolated", func(t *testing.T) {
+		t.Parallel()
+		e := New()
+		assert.NoError(t, e.Set("TEST_ENV_KEY", "test_value"))
+		assert.Equal(t, "test_value", e.Get("TEST_ENV_KEY"))
+		_, ok := os.LookupEnv("TEST_ENV_KEY")
+		assert.False(t, ok)
+		assert.NoError(t, e.Unset("TEST_ENV_KEY"))
+		_, ok = e.Lookup("TEST_ENV_KEY")
+		assert.False(t, ok)
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		t.Parallel()
+		var e Env
+		assert.NoError(t, e.Set("A", "1"))
+		assert.Equal(t, "1", e.Get("A"))
+		assert.NoError(t, e.Unset("A"))
+		assert.NoError(t, e.Unset("B"))
+		assert.Empty(t, e.Keys())
+	})
+
+	t.Run("isolated from process environment", func(t *testing.T) {
+		t.Setenv("TEST_ENV_OS_KEY", "os_value")
+		e := New()
+		_, ok := e.Lookup("TEST_ENV_OS_KEY")
+		assert.False(t, ok)
+	})
+
+	t.Run("overlay", func(t *testing.T) {
+		t.Setenv("TEST_ENV_OS_KEY", "os_value")
+		e := NewOverlay()
+		assert.Equal(t, "os_value", e.Get("TEST_ENV_OS_KEY"))
+		assert.NoError(t, e.Set("TEST_ENV_OS_KEY", "overlay_value"))
+		assert.Equal(t, "overlay_value", e.Get("TEST_ENV_OS_KEY"))
+		assert.Equal(t, "os_value", os.Getenv("TEST_ENV_OS_KEY"))
+		assert.NoError(t, e.Unset("TEST_ENV_OS_KEY"))
+		_, ok := e.Lookup("TEST_ENV_OS_KEY")
+		assert.False(t, ok)
+		assert.NotContains(t, e.Map(), "TEST_ENV_OS_KEY")
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, New().Set("A=B", "value"))
+		assert.Error(t, New().Set("", "value"))
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		t.Parallel()
+		e := FromMap(map[string]string{"INT": "123", "BOOL": "true", "JSON": `{"a":"b"}`})
+		assert.Equal(t, 123, e.MustGetInt("INT"))
+		assert.Equal(t, true, e.MustGetBool("BOOL"))
+		value, err := e.GetIntOr("MISSING", 456)
+		assert.NoError(t, err)
+		assert.Equal(t, 456, value)
+		assert.Equal(t, map[string]string{"a": "b"}, MustGetJSONFrom[map[string]string](e, "JSON"))
+	})
+
+	t.Run("expand", func(t *testing.T) {
+		t.Parallel()
+		e := FromMap(map[string]string{"TEST_ENV_KEY": "test_value"})
+		assert.Equal(t, "test_value", e.Expand("${TEST_ENV_KEY}"))
+		assert.Equal(t, "default_value", e.Expand("${NOT_EXIST_ENV|default_value}"))
+	})
+
+	t.Run("load", func(t *testing.T) {
+		t.Parallel()
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.Load("testdata/.env"))
+		assert.Equal(t, "127.0.0.1", e.Get("DB_HOST"))
+		assert.Equal(t, "root", e.Get("DB_USER"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		t.Parallel()
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.Override("testdata/.env"))
+		assert.Equal(t, "localhost", e.Get("DB_HOST"))
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		t.Parallel()
+		type config struct {
+			Host string `env:"DB_HOST"`
+			Port int    `env:"DB_PORT"`
+		}
+		e := FromMap(map[string]string{"DB_HOST": "localhost", "DB_PORT": "3306"})
+		cfg, err := UnmarshalFrom[config](e)
+		assert.NoError(t, err)
+		assert.Equal(t, config{Host: "localhost", Port: 3306}, cfg)
+	})
+
+	t.Run("lookup typed", func(t *testing.T) {
+		t.Parallel()
+		e := FromMap(map[string]string{"PORT": "8080", "EMPTY": "", "INVALID": "http", "HOSTS": "a,b", "DEBUG": "true"})
+		port, ok, err := e.LookupInt("PORT")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 8080, port)
+		_, ok, err = e.LookupInt("MISSING")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		_, ok, err = e.LookupInt("INVALID")
+		assert.EqualError(t, err, `strconv.Atoi: parsing "http": invalid syntax`)
+		assert.True(t, ok)
+		_, ok, err = e.LookupInt64("EMPTY")
+		assert.Error(t, err)
+		assert.True(t, ok)
+		hosts, ok, err := e.LookupStrings("HOSTS")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"a", "b"}, hosts)
+		debug, ok, err := e.LookupBool("DEBUG")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, debug)
+		value, ok, err := LookupTFrom(e, "PORT", func(s string) (string, error) { return "port " + s, nil })
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "port 8080", value)
+		_, ok, err = LookupJSONFrom[[]int](e, "MISSING")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("empty as unset", func(t *testing.T) {
+		t.Parallel()
+		e := FromMap(map[string]string{"EMPTY": "", "PORT": "8080"})
+		_, ok := e.Lookup("EMPTY")
+		assert.True(t, ok)
+		e.TreatEmptyAsUnset(true)
+		_, ok = e.Lookup("EMPTY")
+		assert.False(t, ok)
+		_, ok, err := e.LookupInt("EMPTY")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		value, err := e.GetIntOr("EMPTY", 80)
+		assert.NoError(t, err)
+		assert.Equal(t, 80, value)
+		assert.Equal(t, "default", e.Expand("${EMPTY-default}"))
+		assert.Equal(t, 8080, e.MustGetInt("PORT"))
+		assert.Contains(t, e.Keys(), "EMPTY")
+	})
+}
@@ -0,0 +1,311 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Expand expands the environment variables in the given string.
+// The string can contain environment variables in the form of the following syntax:
+//   - ${ENV_KEY}: expands to the value of the environment variable ENV_KEY
+//   - ${ENV_KEY|default}: expands to the value of the environment variable ENV_KEY, or the default value if the environment variable is not set
+//   - ${ENV_KEY|FALLBACK_ENV_KEY_1|...|default}: expands to the value of the environment variable ENV_KEY, or the first fallback environment variable which is set, or the default value if all the fallback environment variables are not set
+//
+// Some spacial cases:
+//   - ${ENV_KEY|}: expands to the value of the environment variable ENV_KEY, or the empty string if the environment variable is not set
+//   - ${|default}: equals to default value
+//   - ${|}: equals to the empty string
+//   - ${ENV_KEY|FALLBACK_ENV_KEY|...|}: expands to the value of the environment variable ENV_KEY, or the first fallback environment variable which is set, or the empty string if all the fallback environment variables are not set
+//   - ${ENV_KEY\\|_USERNAME}: env key with `|` character
+//   - ${ENV_KEY|default_\\|value}: default value with `|` character
+//
+// The POSIX/bash parameter expansion syntax is understood as well:
+//   - $ENV_KEY: same as ${ENV_KEY}
+//   - ${ENV_KEY:-default}, ${ENV_KEY-default}: expands to the default value if ENV_KEY is empty or not set, or not set only
+//   - ${ENV_KEY:=default}, ${ENV_KEY=default}: like the above, and assigns the default value to ENV_KEY
+//   - ${ENV_KEY:?message}, ${ENV_KEY?message}: fails with the message if ENV_KEY is empty or not set, or not set only
+//   - ${ENV_KEY:+alternate}, ${ENV_KEY+alternate}: expands to the alternate value if ENV_KEY is set and not empty, or set only
+//   - ${ENV_KEY:offset}, ${ENV_KEY:offset:length}: expands to a substring of the value, negative numbers count from the end
+//   - ${#ENV_KEY}: expands to the length of the value
+//   - ${ENV_KEY#pattern}, ${ENV_KEY##pattern}: removes the shortest, or longest, prefix matching the pattern
+//   - ${ENV_KEY%pattern}, ${ENV_KEY%%pattern}: removes the shortest, or longest, suffix matching the pattern
+//   - ${ENV_KEY^^}, ${ENV_KEY,,}, ${ENV_KEY^}, ${ENV_KEY,}: converts the value, or its first character, to upper or lower case
+//
+// A reference holding a `|` is read with the pipe syntax, see [Dialect] to only understand one of the syntaxes.
+//
+// Default values may hold references themselves, such as ${ENV_KEY:-${FALLBACK_ENV_KEY}}.
+// `$$` and `\$` are expanded to a literal `$`, and inside a reference `\` escapes any character, such as `\}`.
+// Errors are ignored, see [ExpandWithOptions] to get them: invalid references expand to nothing,
+// and unterminated ones are kept as they are.
+//
+// Example:
+//
+//	os.Setenv("ENV_KEY", "value")
+//	os.Setenv("FALLBACK_ENV_KEY_1", "fallback_value_1")
+//	value := Expand("${ENV_KEY}") // value = "value"
+//	value2 := Expand("${ENV_KEY|default}") // value2 = "value"
+//	value3 := Expand("${NOT_FOUND_ENV_KEY|default_value}") // value3 = "default_value"
+//	value4 := Expand("${NOT_FOUND_ENV_KEY|FALLBACK_ENV_KEY_1|}") // value4 = "fallback_value_1"
+//	value5 := Expand("${NOT_FOUND_ENV_KEY|NOT_FOUND_FALLBACK_ENV_KEY_1|default_value}") // value5 = "fallback_value_1"
+func Expand(key string) string {
+	return std.Expand(key)
+}
+
+// ExpandStrict is like Expand but fails when a referenced variable without default value is not set.
+// The returned error is an [*UndefinedError] listing every such reference.
+// References with an explicit default, including an empty one such as ${ENV_KEY|}, never fail.
+func ExpandStrict(s string) (string, error) {
+	return std.ExpandStrict(s)
+}
+
+// UndefinedVariable is a reference to a variable which is not set and has no default value.
+type UndefinedVariable struct {
+	// Name is the name of the referenced variable.
+	Name string
+	// In is the key whose value holds the reference, it is empty when a plain string is expanded.
+	In string
+	// Offset is the byte offset of the reference in the expanded string.
+	Offset int
+	// Line and Column are the 1-based position of the reference in the expanded string.
+	Line, Column int
+	// Filename and FileLine are the file and the 1-based line where In is defined, when it is loaded from a file.
+	Filename string
+	FileLine int
+}
+
+func (v UndefinedVariable) String() string {
+	if v.Filename != "" {
+		return fmt.Sprintf("%s (referenced by %s at %s:%d, %d:%d in the value)", v.Name, v.In, v.Filename, v.FileLine, v.Line, v.Column)
+	}
+	if v.In != "" {
+		return fmt.Sprintf("%s (referenced by %s at %d:%d)", v.Name, v.In, v.Line, v.Column)
+	}
+	return fmt.Sprintf("%s (at %d:%d)", v.Name, v.Line, v.Column)
+}
+
+// UndefinedError is returned by strict expansion when referenced variables are not set.
+type UndefinedError struct {
+	Variables []UndefinedVariable
+}
+
+func (e *UndefinedError) Error() string {
+	names := make([]string, len(e.Variables))
+	for i, v := range e.Variables {
+		names[i] = v.String()
+	}
+	if len(names) == 1 {
+		return "env: undefined variable " + names[0]
+	}
+	return "env: undefined variables " + strings.Join(names, ", ")
+}
+
+// Dialect selects the syntax of the references understood by the expansion.
+type Dialect int
+
+const (
+	// DialectAuto understands both the pipe syntax and the shell syntax.
+	// A reference holding a | is read with the pipe syntax,
+	// and so is a reference which is not valid in the shell syntax.
+	DialectAuto Dialect = iota
+	// DialectPipe only understands the pipe syntax, see [Expand].
+	DialectPipe
+	// DialectShell only understands the shell syntax, see [Expand].
+	DialectShell
+)
+
+// ExpandOptions configures the expansion of variable references.
+type ExpandOptions struct {
+	// Dialect is the syntax of the references.
+	Dialect Dialect
+	// Strict fails with an [*UndefinedError] when a referenced variable without default value is not set.
+	Strict bool
+	// Depth is the number of times the resolved values are expanded again,
+	// when they hold references themselves. The values are not expanded again when it is zero.
+	// References which are still left past this depth are kept as they are.
+	// Variables which reference each other in a cycle fail with a [*CycleError].
+	// The default values are always expanded, whatever the depth.
+	Depth int
+}
+
+// ExpandWithOptions expands the environment variables in the given string as configured by opts.
+// It returns a [*ParameterError] for ${ENV_KEY:?message} references to missing variables,
+// a [*SyntaxError] for references which cannot be parsed, and an [*UndefinedError] in strict mode.
+// The expansion goes on after an error, and the returned string holds the result.
+func ExpandWithOptions(s string, opts ExpandOptions) (string, error) {
+	return std.ExpandWithOptions(s, opts)
+}
+
+// LookupFunc looks up the value of the variable named by the key, and reports whether it is present.
+// It is a [Source] which cannot be enumerated.
+type LookupFunc func(key string) (string, bool)
+
+// Lookup implements [Source].
+func (f LookupFunc) Lookup(key string) (string, bool) {
+	return f(key)
+}
+
+// Keys implements [Source]. It returns nil since a function cannot be enumerated.
+func (f LookupFunc) Keys() []string {
+	return nil
+}
+
+// ExpandWith expands the variables in the given string, resolving them with the lookup function
+// instead of the environment. See [Expand] for the supported syntax.
+//
+// Example:
+//
+//	vars := map[string]string{"DB_HOST": "localhost"}
+//	value := ExpandWith("${DB_HOST}:${DB_PORT|3306}", func(key string) (string, bool) {
+//		v, ok := vars[key]
+//		return v, ok
+//	}) // value = "localhost:3306"
+func ExpandWith(s string, lookup LookupFunc) string {
+	return expand(s, lookup)
+}
+
+// ExpandLookup is like ExpandWith but is configured by opts and returns the errors, see [ExpandWithOptions].
+// The ${ENV_KEY:=default} references do not assign anything.
+func ExpandLookup(s string, lookup LookupFunc, opts ExpandOptions) (string, error) {
+	return expandString(s, lookup, nil, opts)
+}
+
+// ParameterError is returned when a ${ENV_KEY:?message} reference is expanded while ENV_KEY is missing.
+type ParameterError struct {
+	// Name is the name of the referenced variable.
+	Name string
+	// Message is the message of the reference.
+	Message string
+	// Offset is the byte offset of the reference in the expanded string.
+	Offset int
+	// Line and Column are the 1-based position of the reference in the expanded string.
+	Line, Column int
+}
+
+func (e *ParameterError) Error() string {
+	return "env: " + e.Name + ": " + e.Message
+}
+
+// SyntaxError is returned when a reference cannot be parsed.
+type SyntaxError struct {
+	// Reference is the text of the reference.
+	Reference string
+	// Offset is the byte offset of the reference in the expanded string.
+	Offset int
+	// Line and Column are the 1-based position of the reference in the expanded string.
+	Line, Column int
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("env: bad substitution %s at %d:%d: %s", e.Reference, e.Line, e.Column, e.Msg)
+}
+
+func expand(s string, lookup func(string) (string, bool)) string {
+	value, _ := expandString(s, lookup, nil, ExpandOptions{})
+	return value
+}
+
+// expandString expands the references of s with the given lookup function.
+// The assign function is used by ${ENV_KEY:=default} references, they do not assign anything when it is nil.
+func expandString(s string, lookup func(string) (string, bool), assign func(key, value string) error, opts ExpandOptions) (string, error) {
+	nodes, errs := parse(s, opts.Dialect)
+	x := &expansion{src: s, lookup: lookup, assign: assign, opts: opts, errs: errs}
+	value := x.eval(nodes)
+	if len(x.undefined) > 0 {
+		x.errs = append(x.errs, &UndefinedError{Variables: x.undefined})
+	}
+	switch len(x.errs) {
+	case 0:
+		return value, nil
+	case 1:
+		return value, x.errs[0]
+	default:
+		return value, errors.Join(x.errs...)
+	}
+}
+
+// expansion holds the state of the expansion of a string.
+type expansion struct {
+	src       string
+	lookup    func(string) (string, bool)
+	assign    func(key, value string) error
+	opts      ExpandOptions
+	undefined []UndefinedVariable
+	errs      []error
+	// level is the recursion level, and chain the names of the variables being expanded.
+	level int
+	chain []string
+}
+
+// eval returns the expansion of the parsed nodes.
+func (x *expansion) eval(nodes []node) string {
+	if len(nodes) == 1 && nodes[0].ref == nil {
+		return nodes[0].text
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.ref == nil {
+			b.WriteString(n.text)
+		} else if n.ref.pipe {
+			b.WriteString(x.pipe(n.ref))
+		} else {
+			b.WriteString(x.shell(n.ref))
+		}
+	}
+	return b.String()
+}
+
+// pipe expands a reference written with the pipe syntax.
+func (x *expansion) pipe(ref *reference) string {
+	for _, key := range ref.keys {
+		if value, ok := x.lookup(key); ok {
+			return x.recurse(key, value)
+		}
+	}
+	if !ref.withDefault {
+		x.undefinedAt(ref.offset, ref.keys[0])
+	}
+	return x.eval(ref.fallback)
+}
+
+// recurse expands again the value of the named variable as long as the recursion depth is not reached.
+func (x *expansion) recurse(name, s string) string {
+	if x.level >= x.opts.Depth || !strings.Contains(s, "$") {
+		return s
+	}
+	if i := slices.Index(x.chain, name); i >= 0 {
+		x.errs = append(x.errs, &CycleError{Keys: append(slices.Clone(x.chain[i:]), name)})
+		return ""
+	}
+	nodes, errs := parse(s, x.opts.Dialect)
+	child := &expansion{
+		src:    s,
+		lookup: x.lookup,
+		assign: x.assign,
+		opts:   x.opts,
+		errs:   errs,
+		level:  x.level + 1,
+		chain:  append(slices.Clone(x.chain), name),
+	}
+	value := child.eval(nodes)
+	for _, v := range child.undefined {
+		if v.In == "" {
+			v.In = name
+		}
+		x.undefined = append(x.undefined, v)
+	}
+	x.errs = append(x.errs, child.errs...)
+	return value
+}
+
+// undefinedAt records a reference to a variable which is not set, in strict mode.
+func (x *expansion) undefinedAt(offset int, name string) {
+	if !x.opts.Strict {
+		return
+	}
+	line, column := position(x.src, offset)
+	x.undefined = append(x.undefined, UndefinedVariable{Name: name, Offset: offset, Line: line, Column: column})
+}
@@ -0,0 +1,296 @@
+package env
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandStrict(t *testing.T) {
+	e := FromMap(map[string]string{"DB_HOST": "localhost", "EMPTY": ""})
+
+	t.Run("defined", func(t *testing.T) {
+		value, err := e.ExpandStrict("${DB_HOST}:${EMPTY}")
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost:", value)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		value, err := e.ExpandStrict("${DB_PORT|3306} ${DB_USER|}")
+		assert.NoError(t, err)
+		assert.Equal(t, "3306 ", value)
+	})
+
+	t.Run("undefined", func(t *testing.T) {
+		_, err := e.ExpandStrict("host=${DB_HSOT}\nport=$DB_PORT")
+		var undefinedErr *UndefinedError
+		if assert.ErrorAs(t, err, &undefinedErr) {
+			assert.Equal(t, []UndefinedVariable{
+				{Name: "DB_HSOT", Offset: 5, Line: 1, Column: 6},
+				{Name: "DB_PORT", Offset: 21, Line: 2, Column: 6},
+			}, undefinedErr.Variables)
+		}
+		assert.EqualError(t, err, "env: undefined variables DB_HSOT (at 1:6), DB_PORT (at 2:6)")
+	})
+
+	t.Run("load", func(t *testing.T) {
+		e := New()
+		err := e.LoadWithOptions(LoadOptions{Strict: true}, "testdata/load/undefined.env")
+		var undefinedErr *UndefinedError
+		if assert.ErrorAs(t, err, &undefinedErr) {
+			assert.Equal(t, []UndefinedVariable{
+				{Name: "DB_USER", In: "DB_DSN", Offset: 0, Line: 1, Column: 1, Filename: "testdata/load/undefined.env", FileLine: 2},
+				{Name: "DB_HSOT", In: "DB_DSN", Offset: 11, Line: 1, Column: 12, Filename: "testdata/load/undefined.env", FileLine: 2},
+			}, undefinedErr.Variables)
+		}
+		assert.EqualError(t, err, "env: undefined variables "+
+			"DB_USER (referenced by DB_DSN at testdata/load/undefined.env:2, 1:1 in the value), "+
+			"DB_HSOT (referenced by DB_DSN at testdata/load/undefined.env:2, 1:12 in the value)")
+		assert.Empty(t, e.Keys())
+	})
+}
+
+func TestExpandShell(t *testing.T) {
+	e := FromMap(map[string]string{
+		"URL":   "https://user@example.com/path/to/file.tar.gz",
+		"NAME":  "gopi",
+		"EMPTY": "",
+	})
+	for _, tc := range []struct {
+		s, expected string
+	}{
+		{"$NAME", "gopi"},
+		{"${NAME:-default}", "gopi"},
+		{"${EMPTY:-default}", "default"},
+		{"${EMPTY-default}", ""},
+		{"${MISSING-default}", "default"},
+		{"${NAME:+alternate}", "alternate"},
+		{"${EMPTY:+alternate}", ""},
+		{"${EMPTY+alternate}", "alternate"},
+		{"${MISSING+alternate}", ""},
+		{"${NAME:1}", "opi"},
+		{"${NAME:1:2}", "op"},
+		{"${NAME: -3}", "opi"},
+		{"${NAME:1:-1}", "op"},
+		{"${NAME:10}", ""},
+		{"${#NAME}", "4"},
+		{"${URL#*//}", "user@example.com/path/to/file.tar.gz"},
+		{"${URL##*/}", "file.tar.gz"},
+		{"${URL%.*}", "https://user@example.com/path/to/file.tar"},
+		{"${URL%%.*}", "https://user@example"},
+		{"${URL#[a-z]*:}", "//user@example.com/path/to/file.tar.gz"},
+		{"${URL#h?tp*[!a-z]}", "//user@example.com/path/to/file.tar.gz"},
+		{"${URL%\\.*}", "https://user@example.com/path/to/file.tar"},
+		{"${NAME^^}", "GOPI"},
+		{"${NAME^}", "Gopi"},
+		{"${NAME,,}", "gopi"},
+		{"${MISSING|NAME|default}", "gopi"},
+	} {
+		t.Run(tc.s, func(t *testing.T) {
+			value, err := e.ExpandWithOptions(tc.s, ExpandOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, value)
+		})
+	}
+
+	t.Run("long pattern", func(t *testing.T) {
+		e := FromMap(map[string]string{"A": strings.Repeat("a", 2000), "B": strings.Repeat("a", 2000) + "b"})
+		assert.Equal(t, e.Get("A"), e.Expand("${A##*a*a*a*a*a*b}"))
+		assert.Equal(t, e.Get("A"), e.Expand("${A%%*a*a*a*a*a*b}"))
+		assert.Equal(t, "", e.Expand("${B##*a*a*a*a*a*b}"))
+		assert.Equal(t, "b", e.Expand("${B##*a*a*a*a*a}"))
+		assert.Equal(t, strings.Repeat("a", 1995)+"b", e.Expand("${B#*a*a*a*a*a}"))
+	})
+
+	t.Run("assign", func(t *testing.T) {
+		e := FromMap(map[string]string{"EMPTY": ""})
+		assert.Equal(t, "default default", e.Expand("${EMPTY:=default} $EMPTY"))
+		assert.Equal(t, "default", e.Get("EMPTY"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := e.ExpandWithOptions("${EMPTY:?must be set}", ExpandOptions{})
+		assert.EqualError(t, err, "env: EMPTY: must be set")
+		_, err = e.ExpandWithOptions("${EMPTY?must be set}", ExpandOptions{})
+		assert.NoError(t, err)
+		_, err = e.ExpandWithOptions("${MISSING?}", ExpandOptions{})
+		var paramErr *ParameterError
+		if assert.ErrorAs(t, err, &paramErr) {
+			assert.Equal(t, ParameterError{Name: "MISSING", Message: "parameter not set", Line: 1, Column: 1}, *paramErr)
+		}
+	})
+
+	t.Run("dialect", func(t *testing.T) {
+		value, err := e.ExpandWithOptions("${NAME:-default}", ExpandOptions{Dialect: DialectPipe})
+		assert.NoError(t, err)
+		assert.Equal(t, "", value)
+		_, err = e.ExpandWithOptions("${NAME|default}", ExpandOptions{Dialect: DialectShell})
+		var syntaxErr *SyntaxError
+		assert.ErrorAs(t, err, &syntaxErr)
+		value, err = e.ExpandWithOptions("${ NAME }", ExpandOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "gopi", value)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := e.ExpandWithOptions("${MISSING:-default} ${MISSING:+alternate} ${#MISSING}", ExpandOptions{Strict: true})
+		assert.EqualError(t, err, "env: undefined variable MISSING (at 1:43)")
+	})
+}
+
+func TestExpandRecursive(t *testing.T) {
+	e := FromMap(map[string]string{
+		"HOST":    "localhost",
+		"PORT":    "${DEFAULT_PORT:-3306}",
+		"ADDR":    "${HOST}:${PORT}",
+		"DSN":     "tcp(${ADDR})",
+		"A":       "${B}",
+		"B":       "${C|${A}}",
+		"C_UNSET": "${MISSING}",
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		value, err := e.ExpandWithOptions("${ADDR}", ExpandOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "${HOST}:${PORT}", value)
+	})
+
+	t.Run("values", func(t *testing.T) {
+		value, err := e.ExpandWithOptions("${DSN}", ExpandOptions{Depth: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, "tcp(localhost:3306)", value)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		value, err := e.ExpandWithOptions("${MISSING:-${HOST}}", ExpandOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost", value)
+		value, err = e.ExpandWithOptions("${MISSING:-${PORT}}", ExpandOptions{Depth: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, "3306", value)
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		value, err := e.ExpandWithOptions("${DSN}", ExpandOptions{Depth: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, "tcp(${HOST}:${PORT})", value)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := e.ExpandWithOptions("${A}", ExpandOptions{Depth: 10})
+		var cycleErr *CycleError
+		if assert.ErrorAs(t, err, &cycleErr) {
+			assert.Equal(t, []string{"A", "B", "A"}, cycleErr.Keys)
+		}
+		assert.EqualError(t, err, "env: reference cycle A -> B -> A")
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := e.ExpandWithOptions("${C_UNSET}", ExpandOptions{Depth: 10, Strict: true})
+		assert.EqualError(t, err, "env: undefined variable MISSING (referenced by C_UNSET at 1:1)")
+	})
+}
+
+func TestExpandWith(t *testing.T) {
+	vars := map[string]string{"DB_HOST": "localhost"}
+	lookup := func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		t.Setenv("DB_PORT", "5432")
+		assert.Equal(t, "localhost:3306", ExpandWith("${DB_HOST}:${DB_PORT|3306}", lookup))
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		value, err := ExpandLookup("${DB_HOST}:${DB_PORT:=3306}", lookup, ExpandOptions{Strict: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost:3306", value)
+		assert.NotContains(t, vars, "DB_PORT")
+		_, err = ExpandLookup("${DB_HOST}:${DB_PORT}", lookup, ExpandOptions{Strict: true})
+		assert.EqualError(t, err, "env: undefined variable DB_PORT (at 1:12)")
+	})
+
+	t.Run("source", func(t *testing.T) {
+		chain := Chain{MapSource{"DB_PORT": "3306"}, LookupFunc(lookup)}
+		assert.Equal(t, "localhost:3306", ExpandFrom(chain, "${DB_HOST}:${DB_PORT}"))
+		assert.Equal(t, []string{"DB_PORT"}, chain.Keys())
+	})
+}
+
+func TestExpandSyntax(t *testing.T) {
+	e := FromMap(map[string]string{"HOST": "localhost", "PORT": "3306"})
+	for _, tc := range []struct {
+		s, expected string
+	}{
+		{"$$HOST", "$HOST"},
+		{`\$HOST`, "$HOST"},
+		{`\${HOST}`, "${HOST}"},
+		{`C:\path\to\$HOST`, `C:\path\to$HOST`},
+		{"price: 5$", "price: 5$"},
+		{"$ HOST", "$ HOST"},
+		{"${MISSING:-${HOST}:${PORT}}", "localhost:3306"},
+		{"${MISSING|${MISSING_TOO|${HOST}}}", "localhost"},
+		{`${MISSING:-\}}`, "}"},
+		{`${MISSING:-a\$b}`, "a$b"},
+		{`${MISSING|a\|b}`, "a|b"},
+		{"${ HOST }", "localhost"},
+		{"${|}", ""},
+	} {
+		t.Run(tc.s, func(t *testing.T) {
+			value, err := e.ExpandWithOptions(tc.s, ExpandOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, value)
+		})
+	}
+
+	for _, tc := range []struct {
+		s, expected string
+		err         SyntaxError
+	}{
+		{"a${}b", "ab", SyntaxError{Reference: "${}", Offset: 1, Line: 1, Column: 2, Msg: "empty reference"}},
+		{"a\n${ }", "a\n", SyntaxError{Reference: "${ }", Offset: 2, Line: 2, Column: 1, Msg: "empty reference"}},
+		{"${HOST", "${HOST", SyntaxError{Reference: "${HOST", Msg: "unterminated reference", Line: 1, Column: 1}},
+		{"${HOST:-${PORT}", "${HOST:-${PORT}", SyntaxError{Reference: "${HOST:-${PORT}", Msg: "unterminated reference", Line: 1, Column: 1}},
+	} {
+		t.Run(tc.s, func(t *testing.T) {
+			value, err := e.ExpandWithOptions(tc.s, ExpandOptions{})
+			assert.Equal(t, tc.expected, value)
+			var syntaxErr *SyntaxError
+			if assert.ErrorAs(t, err, &syntaxErr) {
+				assert.Equal(t, tc.err, *syntaxErr)
+			}
+			assert.Equal(t, tc.expected, e.Expand(tc.s))
+		})
+	}
+
+	t.Run("shell dialect", func(t *testing.T) {
+		_, err := e.ExpandWithOptions("${HOST:x}", ExpandOptions{Dialect: DialectShell})
+		assert.EqualError(t, err, `env: bad substitution ${HOST:x} at 1:1: invalid substring offset "x"`)
+	})
+}
+
+func FuzzExpand(f *testing.F) {
+	for _, seed := range []string{
+		"", "$", "$$", "${", "${}", "${ }", "${|}", "${A}", "$A", "${A|B|c}", "${A:-${B}}", "${A:1:2}",
+		"${#A}", "${A##*/}", "${A^^}", `\$`, `${A\}`, "${A:?}", "${${A}}", "${A|${B|${C}}",
+	} {
+		f.Add(seed)
+	}
+	lookup := func(key string) (string, bool) {
+		if key == "A" {
+			return "${B}", true
+		}
+		return "", false
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, dialect := range []Dialect{DialectAuto, DialectPipe, DialectShell} {
+			value, _ := ExpandLookup(s, lookup, ExpandOptions{Dialect: dialect, Strict: true, Depth: 3})
+			if !strings.ContainsAny(s, "$") && value != s {
+				t.Errorf("%q without reference expanded to %q", s, value)
+			}
+		}
+	})
+}
@@ -0,0 +1,364 @@
+package env
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of a configuration file.
+type Format int
+
+const (
+	// FormatAuto detects the format from the extension of the file, see [FormatOf].
+	FormatAuto Format = iota
+	// FormatDotenv is the dotenv format, see [Parse].
+	FormatDotenv
+	// FormatJSON is the JSON format.
+	FormatJSON
+	// FormatYAML is the YAML format.
+	FormatYAML
+	// FormatTOML is the TOML format.
+	FormatTOML
+	// FormatINI is the INI format: `key = value` lines grouped in `[section]`s, with ; and # comments.
+	FormatINI
+	// FormatProperties is the Java properties format.
+	FormatProperties
+)
+
+// FormatOf returns the format of the named file from its extension:
+// .json, .yaml or .yml, .toml, .ini, and .properties. Any other file is a dotenv file.
+func FormatOf(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".ini":
+		return FormatINI
+	case ".properties":
+		return FormatProperties
+	}
+	return FormatDotenv
+}
+
+// readFile reads the named configuration file as configured by opts.
+func readFile(filename string, opts LoadOptions) ([]entry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeEntries(f, filename, opts)
+}
+
+// readFSFile reads the named configuration file of the file system as configured by opts.
+func readFSFile(fsys fs.FS, name string, opts LoadOptions) ([]entry, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeEntries(f, name, opts)
+}
+
+// decodeEntries reads the variables of a configuration file in the format configured by opts,
+// or detected from the filename.
+//
+// The documents of the structured formats are flattened: the keys of the nested values are joined with the separator
+// and upper-cased, with the characters which are not valid in a key replaced by _,
+// so {"database": {"host": "localhost"}} is read as DATABASE_HOST=localhost.
+// In the INI and properties formats, the dots of the keys and section names separate the nested keys as well.
+// The arrays of scalars are joined with commas, see [GetStrings], and the other arrays are indexed.
+func decodeEntries(r io.Reader, filename string, opts LoadOptions) ([]entry, error) {
+	format := opts.Format
+	if format == FormatAuto {
+		format = FormatOf(filename)
+	}
+	sep := opts.Separator
+	if sep == "" {
+		sep = "_"
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if isEncryptedFile(data) {
+		key, err := opts.decryptionKey()
+		if err == nil {
+			data, err = Decrypt(data, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w (file %s)", err, filename)
+		}
+	}
+	entries, err := decodeData(data, format, filename, sep, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := decryptEntries(entries, opts.decryptionKey); err != nil {
+		return nil, fmt.Errorf("%w (file %s)", err, filename)
+	}
+	return entries, nil
+}
+
+// decodeData reads the variables of a configuration file in the given format.
+func decodeData(data []byte, format Format, filename, sep string, opts LoadOptions) ([]entry, error) {
+	r := bytes.NewReader(data)
+	var doc any
+	switch format {
+	case FormatDotenv:
+		return parseEntries(r, ParseOptions{Filename: filename, Duplicates: opts.Duplicates, Warn: opts.Warn})
+	case FormatINI:
+		return parseINI(r, filename, sep)
+	case FormatProperties:
+		return parseProperties(r, filename, sep)
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("env: %s: %w", filename, err)
+		}
+	case FormatYAML:
+		if err := yaml.NewDecoder(r).Decode(&doc); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("env: %s: %w", filename, err)
+		}
+	case FormatTOML:
+		if _, err := toml.NewDecoder(r).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("env: %s: %w", filename, err)
+		}
+	default:
+		return nil, fmt.Errorf("env: unknown format %d", format)
+	}
+	var entries []entry
+	flatten(doc, nil, sep, &entries)
+	return entries, nil
+}
+
+// flatten appends the scalars of the decoded value v at the given path.
+func flatten(v any, path []string, sep string, entries *[]entry) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			flatten(v[key], append(slices.Clip(path), key), sep, entries)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = value
+		}
+		flatten(m, path, sep, entries)
+	case []map[string]any:
+		for i, value := range v {
+			flatten(value, append(slices.Clip(path), strconv.Itoa(i)), sep, entries)
+		}
+	case []any:
+		if values, ok := scalars(v); ok {
+			*entries = append(*entries, entry{key: flatKey(path, sep), value: strings.Join(values, ",")})
+			return
+		}
+		for i, value := range v {
+			flatten(value, append(slices.Clip(path), strconv.Itoa(i)), sep, entries)
+		}
+	default:
+		if len(path) > 0 {
+			value, _ := scalar(v)
+			*entries = append(*entries, entry{key: flatKey(path, sep), value: value})
+		}
+	}
+}
+
+// scalars returns the formatted values of an array of scalars, or false if it holds other values.
+func scalars(v []any) ([]string, bool) {
+	values := make([]string, len(v))
+	for i, value := range v {
+		s, ok := scalar(value)
+		if !ok {
+			return nil, false
+		}
+		values[i] = s
+	}
+	return values, true
+}
+
+// scalar formats a scalar value, or returns false if the value is not a scalar.
+func scalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// flatKey returns the key of the nested value at the given path.
+func flatKey(path []string, sep string) string {
+	segments := make([]string, len(path))
+	for i, segment := range path {
+		segments[i] = strings.Map(func(r rune) rune {
+			if r < 0x80 && isAlphaNum(byte(r)) {
+				return r
+			}
+			return '_'
+		}, strings.ToUpper(segment))
+	}
+	return strings.Join(segments, sep)
+}
+
+// parseINI reads the variables of an INI file.
+func parseINI(r io.Reader, filename, sep string) ([]entry, error) {
+	var entries []entry
+	var section []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, string(bom))
+		}
+		switch {
+		case line == "" || line[0] == ';' || line[0] == '#':
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") {
+				return nil, &ParseError{Filename: filename, Line: n, Column: 1, Snippet: scanner.Text(), Msg: "unterminated section"}
+			}
+			section = strings.Split(strings.TrimSpace(line[1:len(line)-1]), ".")
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, &ParseError{Filename: filename, Line: n, Column: 1, Snippet: scanner.Text(), Msg: "missing '=' after key " + key}
+			}
+			value = strings.TrimSpace(value)
+			if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			path := append(slices.Clip(section), strings.Split(strings.TrimSpace(key), ".")...)
+			entries = append(entries, entry{key: flatKey(path, sep), value: value, line: n})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parseProperties reads the variables of a Java properties file.
+func parseProperties(r io.Reader, filename, sep string) ([]entry, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(bytes.TrimPrefix(src, bom)), "\n")
+	var entries []entry
+	for i := 0; i < len(lines); i++ {
+		n := i + 1
+		line := strings.TrimLeft(strings.TrimSuffix(lines[i], "\r"), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// a line ending with an odd number of backslashes continues on the next line
+		for continues(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(strings.TrimSuffix(lines[i], "\r"), " \t\f")
+		}
+		if continues(line) {
+			line = line[:len(line)-1]
+		}
+		end := 0
+		for end < len(line) && !strings.ContainsRune("=: \t\f", rune(line[end])) {
+			if line[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		end = min(end, len(line))
+		key, rest := line[:end], strings.TrimLeft(line[end:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+		key, err := unescapeProperty(key)
+		if err == nil {
+			rest, err = unescapeProperty(rest)
+		}
+		if err != nil {
+			return nil, &ParseError{Filename: filename, Line: n, Column: 1, Snippet: lines[n-1], Msg: err.Error()}
+		}
+		entries = append(entries, entry{key: flatKey(strings.Split(key, "."), sep), value: rest, line: n})
+	}
+	return entries, nil
+}
+
+func continues(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// unescapeProperty replaces the escape sequences of a properties key or value.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid escape sequence %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape sequence %q", s[i-1:i+5])
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,76 @@
+package env
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormats(t *testing.T) {
+	expected := map[string]string{
+		"APP_NAME":       "gopi",
+		"APP_DEBUG":      "true",
+		"APP_URL":        "http://localhost:8080",
+		"DATABASE_HOST":  "localhost",
+		"DATABASE_PORT":  "5432",
+		"DATABASE_DSN":   "postgres://localhost:5432",
+		"HOSTS":          "a,b",
+		"SERVERS_0_NAME": "s1",
+		"SERVERS_1_NAME": "s2",
+		"RATE_LIMIT":     "1.5",
+		"EMPTY":          "",
+	}
+
+	for _, name := range []string{"config.json", "config.yaml", "config.toml", "config.ini", "config.properties"} {
+		t.Run(name, func(t *testing.T) {
+			e := New()
+			assert.NoError(t, e.Load("testdata/formats/"+name))
+			assert.Equal(t, expected, e.Map())
+		})
+	}
+
+	t.Run("separator", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Separator: "__", Prefixes: []string{"DATABASE__"}}, "testdata/formats/config.yaml"))
+		assert.Equal(t, map[string]string{
+			"DATABASE__HOST": "localhost",
+			"DATABASE__PORT": "5432",
+			"DATABASE__DSN":  "postgres://${DATABASE_HOST}:${DATABASE_PORT}",
+		}, e.Map())
+	})
+
+	t.Run("explicit format", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Format: FormatJSON}, "testdata/formats/config.json"))
+		assert.Equal(t, "gopi", e.Get("APP_NAME"))
+		assert.Error(t, New().LoadWithOptions(LoadOptions{Format: FormatJSON}, "testdata/formats/config.toml"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		e := FromMap(map[string]string{"DATABASE_HOST": "db"})
+		assert.NoError(t, e.Load("testdata/formats/config.toml"))
+		assert.Equal(t, "db", e.Get("DATABASE_HOST"))
+		assert.Equal(t, "postgres://db:5432", e.Get("DATABASE_DSN"))
+		assert.NoError(t, e.Override("testdata/formats/config.toml"))
+		assert.Equal(t, "localhost", e.Get("DATABASE_HOST"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := decodeEntries(strings.NewReader("[app\nname=gopi\n"), "config.ini", LoadOptions{})
+		assert.EqualError(t, err, `env: config.ini:1:1: unterminated section: "[app"`)
+		_, err = decodeEntries(strings.NewReader("[app]\nname\n"), "config.ini", LoadOptions{})
+		assert.EqualError(t, err, `env: config.ini:2:1: missing '=' after key name: "name"`)
+		_, err = decodeEntries(strings.NewReader("a=\\u00zz\n"), "config.properties", LoadOptions{})
+		assert.EqualError(t, err, `env: config.properties:1:1: invalid escape sequence "\\u00zz": "a=\\u00zz"`)
+		entries, err := decodeEntries(strings.NewReader("a\\ b=\\u00e9\\n\\\\\n"), "config.properties", LoadOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []entry{{key: "A_B", value: "é\n\\", line: 1}}, entries)
+	})
+
+	t.Run("format of", func(t *testing.T) {
+		assert.Equal(t, FormatYAML, FormatOf("config.YML"))
+		assert.Equal(t, FormatDotenv, FormatOf(".env.local"))
+		assert.Equal(t, FormatProperties, FormatOf("app.properties"))
+	})
+}
@@ -0,0 +1,375 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// LoadOptions configures how dotenv and other configuration files are loaded into an Env.
+// The zero value loads every key of every file without expansion,
+// keeps the values which are already present and fails on missing files.
+type LoadOptions struct {
+	// Override replaces the values which are already present.
+	Override bool
+	// Expand expands the variables referenced by the loaded values, see [Expand].
+	Expand bool
+	// Strict makes the expansion fail with an [*UndefinedError] when a referenced variable
+	// without default value is not set, see [ExpandStrict]. It implies Expand.
+	Strict bool
+	// Dialect is the syntax of the references, see [ExpandOptions].
+	Dialect Dialect
+	// Depth is the depth of the recursive expansion of the values, see [ExpandOptions].
+	Depth int
+	// Duplicates configures how a key defined several times in the same file is handled, see [ParseOptions].
+	// A key defined in several files is not a duplicate: the last file wins.
+	Duplicates DuplicateMode
+	// Warn receives the warnings, see [ParseOptions].
+	Warn func(err error)
+	// Format is the format of the files, detected from their extensions by default, see [FormatOf].
+	Format Format
+	// Separator joins the keys of the nested values of the structured formats, _ by default.
+	// With the default, {"database": {"host": "localhost"}} is loaded as DATABASE_HOST.
+	Separator string
+	// FileSecrets resolves the KEY_FILE variables of the files when it is not nil:
+	// KEY is loaded with the content of the file named by the value of KEY_FILE, see [FileSecretOptions].
+	// KEY_FILE itself is not loaded, so the Env can resolve its own secret files too, see [Env.UseFileSecrets].
+	// The files which define both KEY and KEY_FILE fail to load.
+	FileSecrets *FileSecretOptions
+	// Key decrypts the encrypted files and values, see [Key]. When it is nil, the key is read from the file named by KeyFile,
+	// or else from the variable named by [KeyEnv], or from the file named by the variable KeyEnv+"_FILE".
+	// The decrypted values are not expanded.
+	Key *Key
+	// KeyFile is the name of the file holding the key, see [ReadKeyFile].
+	KeyFile string
+	// Resolvers resolves the loaded values which are references to secrets, such as secret://vault/db#password,
+	// after their expansion. Values are loaded as they are when it is nil, see [Resolvers].
+	Resolvers *Resolvers
+	// Optional skips the files which do not exist instead of failing.
+	Optional bool
+	// Include is the allowlist of keys to load. All keys are loaded when it is empty.
+	Include []string
+	// Exclude is the denylist of keys not to load.
+	Exclude []string
+	// Prefixes restricts the loaded keys to those starting with one of the prefixes.
+	Prefixes []string
+	// TrimPrefix is removed from the beginning of the loaded keys.
+	TrimPrefix string
+	// AddPrefix is prepended to the loaded keys, after TrimPrefix has been removed.
+	AddPrefix string
+
+	// lookupKey returns the key decrypting the files, it is only called when a file is encrypted.
+	lookupKey func() (*Key, error)
+}
+
+// key returns the key under which the file key is loaded, and whether it is loaded at all.
+func (opts LoadOptions) key(key string) (string, bool) {
+	if len(opts.Include) > 0 && !slices.Contains(opts.Include, key) {
+		return "", false
+	}
+	if slices.Contains(opts.Exclude, key) {
+		return "", false
+	}
+	if len(opts.Prefixes) > 0 && !slices.ContainsFunc(opts.Prefixes, func(prefix string) bool {
+		return strings.HasPrefix(key, prefix)
+	}) {
+		return "", false
+	}
+	return opts.AddPrefix + strings.TrimPrefix(key, opts.TrimPrefix), true
+}
+
+// LoadWithOptions loads the named file(s) into the Env as configured by opts.
+// If no file is named, it loads ".env".
+// When several files define the same key, the last one wins.
+// Besides dotenv files, JSON, YAML, TOML, INI and Java properties files are loaded,
+// their nested values being flattened into keys, see [LoadOptions].
+//
+// When expansion is enabled, the values are expanded in dependency order,
+// so a value always sees the values of the keys it references from any of the loaded files,
+// whatever the order they are defined in. A reference to a key which is already present
+// and not overridden resolves to the present value. Single-quoted values are not expanded.
+// If the values reference each other in a cycle, a [*CycleError] is returned and nothing is loaded.
+func (e *Env) LoadWithOptions(opts LoadOptions, filenames ...string) error {
+	return e.load(opts, readFile, filenames)
+}
+
+// LoadFSWithOptions loads the named file(s) of the file system into the Env as configured by opts.
+// If no file is named, it loads ".env". See [Env.LoadWithOptions].
+func (e *Env) LoadFSWithOptions(fsys fs.FS, opts LoadOptions, names ...string) error {
+	return e.load(opts, func(name string, opts LoadOptions) ([]entry, error) {
+		return readFSFile(fsys, name, opts)
+	}, names)
+}
+
+// load reads the named files with the read function, and loads them into the Env.
+func (e *Env) load(opts LoadOptions, read func(name string, opts LoadOptions) ([]entry, error), names []string) error {
+	if len(names) == 0 {
+		names = append(names, ".env")
+	}
+	var key *Key
+	opts.lookupKey = func() (*Key, error) {
+		var err error
+		if key == nil {
+			key, err = e.decryptionKey(opts)
+		}
+		return key, err
+	}
+	var batch []entry
+	index := make(map[string]int)
+	for _, name := range names {
+		entries, err := read(name, opts)
+		if err != nil {
+			if opts.Optional && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		for _, ent := range entries {
+			ent.filename = name
+			if i, ok := index[ent.key]; ok {
+				batch[i] = ent
+				continue
+			}
+			index[ent.key] = len(batch)
+			batch = append(batch, ent)
+		}
+	}
+	return e.apply(opts, batch)
+}
+
+// apply sets the entries of a batch into the Env.
+func (e *Env) apply(opts LoadOptions, batch []entry) error {
+	var current map[string]string
+	if !opts.Override {
+		current = e.Map()
+	}
+	// targets holds the key every file key is loaded under, whether it is loaded at all,
+	// and whether the value already present under that key is kept.
+	type target struct {
+		key        string
+		load, kept bool
+	}
+	targets := make(map[string]target, len(batch))
+	targetOf := func(key string) target {
+		t, ok := targets[key]
+		if !ok {
+			t.key, t.load = opts.key(key)
+			if t.load {
+				_, t.kept = current[t.key]
+			}
+			targets[key] = t
+		}
+		return t
+	}
+	kept := func(key string) bool {
+		return targetOf(key).kept
+	}
+	values := make(map[string]string, len(batch))
+	for _, ent := range batch {
+		if t := targetOf(ent.key); t.kept {
+			values[ent.key] = current[t.key]
+		} else {
+			values[ent.key] = ent.value
+		}
+	}
+	if opts.Expand || opts.Strict {
+		order, err := dependencyOrder(batch, kept, opts.Dialect)
+		if err != nil {
+			return err
+		}
+		// the ${KEY:=default} references assign the batch, so the values they assign are loaded like the others
+		assign := func(ent entry) func(key, value string) error {
+			return func(key, value string) error {
+				if _, ok := values[key]; !ok {
+					batch = append(batch, entry{key: key, value: value, literal: true, line: ent.line, filename: ent.filename})
+				}
+				values[key] = value
+				return nil
+			}
+		}
+		var undefined []UndefinedVariable
+		for _, ent := range order {
+			if kept(ent.key) || ent.literal {
+				continue
+			}
+			value, err := expandString(ent.expression(), func(key string) (string, bool) {
+				if value, ok := values[key]; ok && key != ent.key {
+					return value, true
+				}
+				return e.Lookup(key)
+			}, assign(ent), ExpandOptions{Dialect: opts.Dialect, Strict: opts.Strict, Depth: opts.Depth})
+			if undefinedErr, ok := err.(*UndefinedError); ok {
+				for _, v := range undefinedErr.Variables {
+					if v.In == "" {
+						v.In, v.Filename, v.FileLine = ent.key, ent.filename, ent.line
+					}
+					undefined = append(undefined, v)
+				}
+			} else if err != nil {
+				return err
+			}
+			values[ent.key] = value
+		}
+		if len(undefined) > 0 {
+			return &UndefinedError{Variables: undefined}
+		}
+	}
+	if opts.FileSecrets != nil {
+		var err error
+		if batch, err = resolveFileSecrets(batch, values, kept, opts.FileSecrets); err != nil {
+			return err
+		}
+	}
+	if opts.Resolvers != nil {
+		for _, ent := range batch {
+			if kept(ent.key) {
+				continue
+			}
+			value, err := opts.Resolvers.resolve(context.Background(), values[ent.key])
+			if err != nil {
+				return fmt.Errorf("env: %s: %w", ent.key, err)
+			}
+			values[ent.key] = value
+		}
+	}
+	for _, ent := range batch {
+		t := targetOf(ent.key)
+		if !t.load || t.kept {
+			continue
+		}
+		if err := e.Set(t.key, values[ent.key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CycleError is returned when variables reference each other in a cycle.
+type CycleError struct {
+	// Keys is the chain of references, starting and ending with the same key.
+	Keys []string
+}
+
+func (e *CycleError) Error() string {
+	return "env: reference cycle " + strings.Join(e.Keys, " -> ")
+}
+
+// dependencyOrder sorts the entries so that every entry comes after the entries its value references.
+// Entries which do not depend on each other keep their original order.
+// A reference of an entry to itself refers to the value the key had before, and is not a dependency.
+// The entries which are kept are not expanded, so their references are not dependencies either.
+func dependencyOrder(batch []entry, kept func(key string) bool, dialect Dialect) ([]entry, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	index := make(map[string]int, len(batch))
+	for i, ent := range batch {
+		index[ent.key] = i
+	}
+	state := make([]int, len(batch))
+	order := make([]entry, 0, len(batch))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, batch[i].key)
+			return &CycleError{Keys: append(slices.Clone(path[start:]), batch[i].key)}
+		}
+		state[i] = visiting
+		path = append(path, batch[i].key)
+		if !batch[i].literal && !kept(batch[i].key) {
+			for _, key := range references(batch[i].expression(), dialect) {
+				if j, ok := index[key]; ok && j != i {
+					if err := visit(j); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		order = append(order, batch[i])
+		return nil
+	}
+	for i := range batch {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Load loads the named file(s) into the Env.
+// Variables which are already present are not overridden.
+func (e *Env) Load(filenames ...string) error {
+	return e.LoadWithOptions(LoadOptions{Expand: true}, filenames...)
+}
+
+// Override loads the named file(s) into the Env, overriding any existing values.
+// Unlike Load, it does nothing if no file is named.
+func (e *Env) Override(filenames ...string) error {
+	if len(filenames) == 0 {
+		return nil
+	}
+	return e.LoadWithOptions(LoadOptions{Override: true, Expand: true}, filenames...)
+}
+
+// LoadFS loads the named file(s) of the file system into the Env, such as embedded files.
+// Variables which are already present are not overridden.
+func (e *Env) LoadFS(fsys fs.FS, names ...string) error {
+	return e.LoadFSWithOptions(fsys, LoadOptions{Expand: true}, names...)
+}
+
+// OverrideFS loads the named file(s) of the file system into the Env, overriding any existing values.
+// Unlike LoadFS, it does nothing if no file is named.
+func (e *Env) OverrideFS(fsys fs.FS, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return e.LoadFSWithOptions(fsys, LoadOptions{Override: true, Expand: true}, names...)
+}
+
+// LoadWithOptions loads the named file(s) into the environment as configured by opts.
+func LoadWithOptions(opts LoadOptions, filenames ...string) error {
+	return std.LoadWithOptions(opts, filenames...)
+}
+
+// Load loads the named file(s) into the environment.
+func Load(filenames ...string) error {
+	return std.Load(filenames...)
+}
+
+// Override loads the named file(s) into the environment, overriding any existing values.
+// Unlike Load, it does nothing if no file is named.
+func Override(filenames ...string) error {
+	return std.Override(filenames...)
+}
+
+// LoadFSWithOptions loads the named file(s) of the file system into the environment as configured by opts.
+func LoadFSWithOptions(fsys fs.FS, opts LoadOptions, names ...string) error {
+	return std.LoadFSWithOptions(fsys, opts, names...)
+}
+
+// LoadFS loads the named file(s) of the file system into the environment, such as embedded files:
+//
+//	//go:embed defaults.env
+//	var defaults embed.FS
+//
+//	err := env.LoadFS(defaults, "defaults.env")
+func LoadFS(fsys fs.FS, names ...string) error {
+	return std.LoadFS(fsys, names...)
+}
+
+// OverrideFS loads the named file(s) of the file system into the environment, overriding any existing values.
+// Unlike LoadFS, it does nothing if no file is named.
+func OverrideFS(fsys fs.FS, names ...string) error {
+	return std.OverrideFS(fsys, names...)
+}
@@ -0,0 +1,190 @@
+package env
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWithOptions(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		e := New()
+		assert.Error(t, e.LoadWithOptions(LoadOptions{}, "testdata/load/missing.env"))
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Optional: true}, "testdata/load/missing.env", "testdata/load/.env"))
+		assert.Equal(t, "gopi", e.Get("APP_NAME"))
+	})
+
+	t.Run("keep existing", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{}, "testdata/load/.env"))
+		assert.Equal(t, "127.0.0.1", e.Get("DB_HOST"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Override: true}, "testdata/load/.env"))
+		assert.Equal(t, "localhost", e.Get("DB_HOST"))
+	})
+
+	t.Run("override without files", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("DB_HOST=localhost\n"), 0o600))
+		chdir(t, dir)
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.Override())
+		assert.Equal(t, "127.0.0.1", e.Get("DB_HOST"))
+		assert.NoError(t, New().Override())
+	})
+
+	t.Run("override fs without files", func(t *testing.T) {
+		fsys := fstest.MapFS{".env": {Data: []byte("DB_HOST=localhost\n")}}
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.OverrideFS(fsys))
+		assert.Equal(t, "127.0.0.1", e.Get("DB_HOST"))
+		assert.NoError(t, e.OverrideFS(fsys, ".env"))
+		assert.Equal(t, "localhost", e.Get("DB_HOST"))
+	})
+
+	t.Run("expand", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{}, "testdata/load/.env"))
+		assert.Equal(t, "${DB_HOST}:3306", e.Get("DB_URL"))
+		e = FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Expand: true}, "testdata/load/.env"))
+		assert.Equal(t, "127.0.0.1:3306", e.Get("DB_URL"))
+	})
+
+	t.Run("include and exclude", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Include: []string{"APP_NAME", "SECRET"}, Exclude: []string{"SECRET"}}, "testdata/load/.env"))
+		assert.Equal(t, []string{"APP_NAME"}, e.Keys())
+	})
+
+	t.Run("prefixes", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Prefixes: []string{"DB_"}, TrimPrefix: "DB_", AddPrefix: "MYSQL_"}, "testdata/load/.env"))
+		assert.ElementsMatch(t, []string{"MYSQL_HOST", "MYSQL_PORT", "MYSQL_URL"}, e.Keys())
+		assert.Equal(t, "localhost", e.Get("MYSQL_HOST"))
+	})
+
+	t.Run("prefixes with existing values", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1", "PORT": "1"})
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Prefixes: []string{"DB_"}, TrimPrefix: "DB_", Expand: true}, "testdata/load/.env"))
+		assert.Equal(t, "localhost", e.Get("HOST"))
+		assert.Equal(t, "1", e.Get("PORT"))
+		assert.Equal(t, "localhost:3306", e.Get("URL"))
+		assert.Equal(t, "127.0.0.1", e.Get("DB_HOST"))
+	})
+
+	t.Run("assign", func(t *testing.T) {
+		fsys := fstest.MapFS{".env": {Data: []byte("APP_X=${UNSET_Y:=oops}\nAPP_Z=${APP_W:=z}${UNSET_Y}\n")}}
+		e := New()
+		assert.NoError(t, e.LoadFSWithOptions(fsys, LoadOptions{Prefixes: []string{"APP_"}, Expand: true}))
+		assert.Equal(t, map[string]string{"APP_X": "oops", "APP_Z": "zoops", "APP_W": "z"}, e.Map())
+
+		e = New()
+		fsys["strict.env"] = &fstest.MapFile{Data: []byte("APP_V=${UNDEFINED}\n")}
+		assert.Error(t, e.LoadFSWithOptions(fsys, LoadOptions{Expand: true, Strict: true}, ".env", "strict.env"))
+		assert.Empty(t, e.Keys())
+	})
+}
+
+func TestLoadDependencyOrder(t *testing.T) {
+	t.Run("references across files", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			e := New()
+			assert.NoError(t, e.Load("testdata/load/ordered.env", "testdata/load/ordered.local.env"))
+			assert.Equal(t, "root@localhost:5432", e.Get("DB_DSN"))
+		}
+	})
+
+	t.Run("existing values", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.Load("testdata/load/ordered.env"))
+		assert.Equal(t, "root@127.0.0.1:", e.Get("DB_DSN"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1", "DB_HOST_OVERRIDE": "db"})
+		assert.NoError(t, e.Override("testdata/load/ordered.env"))
+		assert.Equal(t, "db", e.Get("DB_HOST"))
+		assert.Equal(t, "root@db:", e.Get("DB_DSN"))
+	})
+
+	t.Run("self reference", func(t *testing.T) {
+		e := FromMap(map[string]string{"PATH_SUFFIX": "/sbin"})
+		assert.NoError(t, e.Override("testdata/load/ordered.env"))
+		assert.Equal(t, "/sbin:/usr/bin", e.Get("PATH_SUFFIX"))
+	})
+
+	t.Run("literal", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.Load("testdata/load/ordered.env"))
+		assert.Equal(t, "${DB_USER}", e.Get("LITERAL"))
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		e := New()
+		err := e.Load("testdata/load/cycle.env")
+		var cycleErr *CycleError
+		if assert.ErrorAs(t, err, &cycleErr) {
+			assert.Equal(t, []string{"A", "B", "C", "A"}, cycleErr.Keys)
+			assert.EqualError(t, err, "env: reference cycle A -> B -> C -> A")
+		}
+		assert.Empty(t, e.Keys())
+
+		e = FromMap(map[string]string{"A": "1", "B": "2"})
+		assert.NoError(t, e.LoadFS(fstest.MapFS{".env": {Data: []byte("A=${B}\nB=${A}\n")}}))
+		assert.Equal(t, "1", e.Get("A"))
+		assert.Equal(t, "2", e.Get("B"))
+
+		e = FromMap(map[string]string{"A": "1"})
+		assert.NoError(t, e.Load("testdata/load/cycle.env"))
+		assert.Equal(t, map[string]string{"A": "1", "B": "1", "C": "1"}, e.Map())
+	})
+}
+
+//go:embed testdata/load/.env testdata/formats/config.json
+var loadFS embed.FS
+
+func TestLoadFS(t *testing.T) {
+	t.Run("embed", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_HOST": "127.0.0.1"})
+		assert.NoError(t, e.LoadFS(loadFS, "testdata/load/.env", "testdata/formats/config.json"))
+		assert.Equal(t, "gopi", e.Get("APP_NAME"))
+		assert.Equal(t, "127.0.0.1", e.Get("DB_HOST"))
+		assert.Equal(t, "127.0.0.1:3306", e.Get("DB_URL"))
+		assert.Equal(t, "postgres://localhost:5432", e.Get("DATABASE_DSN"))
+	})
+
+	t.Run("map", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			".env":       {Data: []byte("A=1\nB=${A}2\n")},
+			".env.local": {Data: []byte("A=3\n")},
+		}
+		e := FromMap(map[string]string{"A": "0"})
+		assert.NoError(t, e.LoadFS(fsys))
+		assert.Equal(t, map[string]string{"A": "0", "B": "02"}, e.Map())
+		assert.NoError(t, e.OverrideFS(fsys, ".env", ".env.local"))
+		assert.Equal(t, map[string]string{"A": "3", "B": "32"}, e.Map())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		fsys := fstest.MapFS{".env": {Data: []byte("A=1\n")}}
+		assert.ErrorIs(t, New().LoadFS(fsys, "missing.env"), fs.ErrNotExist)
+		e := New()
+		assert.NoError(t, e.LoadFSWithOptions(fsys, LoadOptions{Optional: true}, "missing.env", ".env"))
+		assert.Equal(t, "1", e.Get("A"))
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		fsys := fstest.MapFS{"config/.env": {Data: []byte("A\n")}}
+		err := New().LoadFS(fsys, "config/.env")
+		assert.EqualError(t, err, `env: config/.env:1:2: missing '=' after key A: "A"`)
+	})
+}
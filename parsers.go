@@ -0,0 +1,176 @@
+package env
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// parsers holds the parsers of [GetAs] by type, see [RegisterParser].
+var parsers = struct {
+	sync.RWMutex
+	m map[reflect.Type]any
+}{m: map[reflect.Type]any{
+	reflect.TypeFor[time.Duration](): time.ParseDuration,
+	reflect.TypeFor[*url.URL]():      url.Parse,
+	reflect.TypeFor[url.URL]():       parseURL,
+	reflect.TypeFor[[]string]():      splitStrings,
+}}
+
+func parseURL(s string) (url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return url.URL{}, err
+	}
+	return *u, nil
+}
+
+// RegisterParser registers the function parsing the values of type T for [GetAs] and the functions alike,
+// replacing the parser of T already registered if any.
+func RegisterParser[T any](parse func(s string) (T, error)) {
+	parsers.Lock()
+	defer parsers.Unlock()
+	parsers.m[reflect.TypeFor[T]()] = parse
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// parserOf returns the parser of the values of type T, which is:
+//   - the parser registered for T, see [RegisterParser],
+//   - or else the UnmarshalText method of *T, if it implements [encoding.TextUnmarshaler],
+//   - or else the strconv function parsing the kind of T, if it is a boolean, a number or a string.
+func parserOf[T any]() (func(s string) (T, error), error) {
+	typ := reflect.TypeFor[T]()
+	parsers.RLock()
+	parse, ok := parsers.m[typ]
+	parsers.RUnlock()
+	if ok {
+		return parse.(func(s string) (T, error)), nil
+	}
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return func(s string) (T, error) {
+			var value T
+			err := any(&value).(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+			return value, err
+		}, nil
+	}
+	var set func(v reflect.Value, s string) error
+	switch typ.Kind() {
+	case reflect.Bool:
+		set = func(v reflect.Value, s string) error {
+			b, err := strconv.ParseBool(s)
+			v.SetBool(b)
+			return err
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		set = func(v reflect.Value, s string) error {
+			n, err := strconv.ParseInt(s, 10, typ.Bits())
+			v.SetInt(n)
+			return err
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		set = func(v reflect.Value, s string) error {
+			n, err := strconv.ParseUint(s, 10, typ.Bits())
+			v.SetUint(n)
+			return err
+		}
+	case reflect.Float32, reflect.Float64:
+		set = func(v reflect.Value, s string) error {
+			f, err := strconv.ParseFloat(s, typ.Bits())
+			v.SetFloat(f)
+			return err
+		}
+	case reflect.Complex64, reflect.Complex128:
+		set = func(v reflect.Value, s string) error {
+			c, err := strconv.ParseComplex(s, typ.Bits())
+			v.SetComplex(c)
+			return err
+		}
+	case reflect.String:
+		set = func(v reflect.Value, s string) error {
+			v.SetString(s)
+			return nil
+		}
+	default:
+		return nil, fmt.Errorf("env: no parser for type %s", typ)
+	}
+	return func(s string) (T, error) {
+		var value T
+		if err := set(reflect.ValueOf(&value).Elem(), s); err != nil {
+			var zero T
+			return zero, err
+		}
+		return value, nil
+	}, nil
+}
+
+// GetAsFrom returns the value of the variable named by the key in the given source,
+// converted to the type T by the parser of T, see [RegisterParser].
+func GetAsFrom[T any](src Source, key string) (T, error) {
+	parse, err := parserOf[T]()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return GetTFrom(src, key, parse)
+}
+
+// MustGetAsFrom is like GetAsFrom but panics if the value cannot be converted.
+func MustGetAsFrom[T any](src Source, key string) T {
+	value, err := GetAsFrom[T](src, key)
+	if err == nil {
+		return value
+	}
+	panic(err)
+}
+
+// GetAsOrFrom is like GetAsFrom but returns the default value if the variable is not present.
+// If the value cannot be converted, it returns the default value and an error.
+func GetAsOrFrom[T any](src Source, key string, defaultValue T) (T, error) {
+	parse, err := parserOf[T]()
+	if err != nil {
+		return defaultValue, err
+	}
+	return GetTOrFrom(src, key, parse, defaultValue)
+}
+
+// LookupAsFrom is like GetAsFrom but also reports whether the variable is present, see [LookupTFrom].
+func LookupAsFrom[T any](src Source, key string) (T, bool, error) {
+	parse, err := parserOf[T]()
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+	return LookupTFrom(src, key, parse)
+}
+
+// GetAs returns the value of the environment variable named by the key, converted to the type T
+// by the parser of T, see [RegisterParser]. It supports the booleans, numbers and strings,
+// [time.Duration], [time.Time], [url.URL], [net.IP], [netip.Addr], [netip.Prefix],
+// comma-separated []string and every type implementing [encoding.TextUnmarshaler]:
+//
+//	port, err := env.GetAs[uint16]("PORT")
+//	addr, err := env.GetAs[netip.Addr]("LISTEN_ADDR")
+func GetAs[T any](key string) (T, error) {
+	return GetAsFrom[T](std, key)
+}
+
+// MustGetAs is like GetAs but panics if the value cannot be converted.
+func MustGetAs[T any](key string) T {
+	return MustGetAsFrom[T](std, key)
+}
+
+// GetAsOr is like GetAs but returns the default value if the variable is not present.
+// If the value cannot be converted, it returns the default value and an error.
+func GetAsOr[T any](key string, defaultValue T) (T, error) {
+	return GetAsOrFrom(std, key, defaultValue)
+}
+
+// LookupAs is like GetAs but also reports whether the variable is present, see [LookupTFrom].
+func LookupAs[T any](key string) (T, bool, error) {
+	return LookupAsFrom[T](std, key)
+}
@@ -0,0 +1,119 @@
+package env
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type level int
+
+type point struct{ x, y string }
+
+type upper string
+
+func (u *upper) UnmarshalText(text []byte) error {
+	*u = upper(strings.ToUpper(string(text)))
+	return nil
+}
+
+func TestGetAs(t *testing.T) {
+	e := FromMap(map[string]string{
+		"INT":      "-42",
+		"UINT16":   "8080",
+		"INT8":     "300",
+		"FLOAT":    "1.5",
+		"COMPLEX":  "1+2i",
+		"BOOL":     "true",
+		"STRING":   "hello",
+		"LEVEL":    "3",
+		"DURATION": "1m30s",
+		"TIME":     "2024-01-02T03:04:05Z",
+		"URL":      "https://example.com/path",
+		"IP":       "10.0.0.1",
+		"ADDR":     "::1",
+		"PREFIX":   "10.0.0.0/8",
+		"HOSTS":    "a,b",
+		"UPPER":    "abc",
+		"POINT":    "1:2",
+	})
+
+	t.Run("builtin", func(t *testing.T) {
+		assert.Equal(t, -42, MustGetAsFrom[int](e, "INT"))
+		assert.Equal(t, uint16(8080), MustGetAsFrom[uint16](e, "UINT16"))
+		assert.Equal(t, 1.5, MustGetAsFrom[float64](e, "FLOAT"))
+		assert.Equal(t, float32(1.5), MustGetAsFrom[float32](e, "FLOAT"))
+		assert.Equal(t, 1+2i, MustGetAsFrom[complex128](e, "COMPLEX"))
+		assert.Equal(t, complex64(1+2i), MustGetAsFrom[complex64](e, "COMPLEX"))
+		assert.Equal(t, true, MustGetAsFrom[bool](e, "BOOL"))
+		assert.Equal(t, "hello", MustGetAsFrom[string](e, "STRING"))
+		assert.Equal(t, level(3), MustGetAsFrom[level](e, "LEVEL"))
+		assert.Equal(t, 90*time.Second, MustGetAsFrom[time.Duration](e, "DURATION"))
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), MustGetAsFrom[time.Time](e, "TIME"))
+		assert.Equal(t, "example.com", MustGetAsFrom[url.URL](e, "URL").Host)
+		assert.Equal(t, "/path", MustGetAsFrom[*url.URL](e, "URL").Path)
+		assert.Equal(t, net.ParseIP("10.0.0.1"), MustGetAsFrom[net.IP](e, "IP"))
+		assert.Equal(t, netip.IPv6Loopback(), MustGetAsFrom[netip.Addr](e, "ADDR"))
+		assert.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), MustGetAsFrom[netip.Prefix](e, "PREFIX"))
+		assert.Equal(t, []string{"a", "b"}, MustGetAsFrom[[]string](e, "HOSTS"))
+		assert.Equal(t, upper("ABC"), MustGetAsFrom[upper](e, "UPPER"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := GetAsFrom[int8](e, "INT8")
+		assert.EqualError(t, err, `strconv.ParseInt: parsing "300": value out of range`)
+		_, err = GetAsFrom[netip.Addr](e, "STRING")
+		assert.Error(t, err)
+		_, err = GetAsFrom[complex128](e, "STRING")
+		assert.Error(t, err)
+		_, err = GetAsFrom[[]int](e, "POINT")
+		assert.EqualError(t, err, "env: no parser for type []int")
+		assert.Panics(t, func() { MustGetAsFrom[int](e, "STRING") })
+	})
+
+	t.Run("or", func(t *testing.T) {
+		value, err := GetAsOrFrom(e, "MISSING", 8080)
+		assert.NoError(t, err)
+		assert.Equal(t, 8080, value)
+		value, err = GetAsOrFrom(e, "STRING", 8080)
+		assert.Error(t, err)
+		assert.Equal(t, 8080, value)
+		d, err := GetAsOrFrom(e, "DURATION", time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 90*time.Second, d)
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		d, ok, err := LookupAsFrom[time.Duration](e, "DURATION")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 90*time.Second, d)
+		_, ok, err = LookupAsFrom[time.Duration](e, "MISSING")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("register", func(t *testing.T) {
+		RegisterParser(func(s string) (point, error) {
+			x, y, _ := strings.Cut(s, ":")
+			return point{x, y}, nil
+		})
+		assert.Equal(t, point{"1", "2"}, MustGetAsFrom[point](e, "POINT"))
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("ENV_TEST_GET_AS", "8080")
+		assert.Equal(t, 8080, MustGetAs[int]("ENV_TEST_GET_AS"))
+		port, err := GetAs[uint16]("ENV_TEST_GET_AS")
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(8080), port)
+		timeout, err := GetAsOr("ENV_TEST_GET_AS_MISSING", time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second, timeout)
+	})
+}
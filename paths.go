@@ -0,0 +1,127 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// AppDir is a named directory of the application, such as its configuration directory, see [Env.Path].
+type AppDir struct {
+	// Name is the name of the directory under APP_ROOT, such as "config".
+	Name string
+	// Env is the variable overriding the directory, such as APP_CONFIG_DIR.
+	Env string
+	// XDG is the XDG Base Directory variable the directory falls back to, such as XDG_CONFIG_HOME.
+	XDG string
+}
+
+// The named directories of the application.
+var (
+	StorageDir = AppDir{Name: "storage", Env: "APP_STORAGE_DIR", XDG: "XDG_DATA_HOME"}
+	ConfigDir  = AppDir{Name: "config", Env: "APP_CONFIG_DIR", XDG: "XDG_CONFIG_HOME"}
+	LogsDir    = AppDir{Name: "logs", Env: "APP_LOGS_DIR", XDG: "XDG_STATE_HOME"}
+	CacheDir   = AppDir{Name: "cache", Env: "APP_CACHE_DIR", XDG: "XDG_CACHE_HOME"}
+)
+
+// xdgDefaults are the directories under HOME the XDG Base Directory variables default to.
+var xdgDefaults = map[string]string{
+	"XDG_DATA_HOME":   filepath.Join(".local", "share"),
+	"XDG_CONFIG_HOME": ".config",
+	"XDG_STATE_HOME":  filepath.Join(".local", "state"),
+	"XDG_CACHE_HOME":  ".cache",
+}
+
+// RootPath returns the path under the root directory of the application, the value of APP_ROOT,
+// or the directory [Env.Bootstrap] would set it to when it is not set.
+func (e *Env) RootPath(elem ...string) string {
+	root := e.Get("APP_ROOT")
+	if root == "" {
+		var err error
+		if root, err = rootDir(e.WorkPath()); err != nil {
+			root = e.WorkPath()
+		}
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// WorkPath returns the path under the working directory of the application, the value of APP_WD,
+// or the current directory when it is not set.
+func (e *Env) WorkPath(elem ...string) string {
+	wd := e.Get("APP_WD")
+	if wd == "" {
+		var err error
+		if wd, err = os.Getwd(); err != nil {
+			wd = "."
+		}
+	}
+	return filepath.Join(append([]string{wd}, elem...)...)
+}
+
+// Path returns the path under the named directory of the application, which is:
+//   - the value of the dir.Env variable, relative to the root directory when it is not absolute,
+//   - or else, when APP_NAME is set, the APP_NAME directory under the XDG Base Directory named by dir.XDG.
+//     On Linux, an unset or relative XDG variable defaults to a directory under HOME as the specification says,
+//     such as $HOME/.config for XDG_CONFIG_HOME. On the other systems, it is only used when it is set,
+//   - or else dir.Name under the root directory, see [Env.RootPath].
+func (e *Env) Path(dir AppDir, elem ...string) string {
+	base := e.Get(dir.Env)
+	switch {
+	case base != "":
+		if !filepath.IsAbs(base) {
+			base = e.RootPath(base)
+		}
+	case e.Get("APP_NAME") != "" && e.xdgHome(dir.XDG) != "":
+		base = filepath.Join(e.xdgHome(dir.XDG), e.Get("APP_NAME"))
+	default:
+		base = e.RootPath(dir.Name)
+	}
+	return filepath.Join(append([]string{base}, elem...)...)
+}
+
+// xdgHome returns the XDG Base Directory named by the variable, or an empty string if there is none.
+func (e *Env) xdgHome(key string) string {
+	if dir := e.Get(key); filepath.IsAbs(dir) {
+		return dir
+	}
+	home := e.Get("HOME")
+	if runtime.GOOS != "linux" || xdgDefaults[key] == "" || !filepath.IsAbs(home) {
+		return ""
+	}
+	return filepath.Join(home, xdgDefaults[key])
+}
+
+// RootPath returns the path under the root directory of the application, see [Env.RootPath].
+func RootPath(elem ...string) string {
+	return std.RootPath(elem...)
+}
+
+// WorkPath returns the path under the working directory of the application, see [Env.WorkPath].
+func WorkPath(elem ...string) string {
+	return std.WorkPath(elem...)
+}
+
+// Path returns the path under the named directory of the application, see [Env.Path].
+func Path(dir AppDir, elem ...string) string {
+	return std.Path(dir, elem...)
+}
+
+// StoragePath returns the path under the storage directory of the application, see [StorageDir].
+func StoragePath(elem ...string) string {
+	return std.Path(StorageDir, elem...)
+}
+
+// ConfigPath returns the path under the configuration directory of the application, see [ConfigDir].
+func ConfigPath(elem ...string) string {
+	return std.Path(ConfigDir, elem...)
+}
+
+// LogsPath returns the path under the logs directory of the application, see [LogsDir].
+func LogsPath(elem ...string) string {
+	return std.Path(LogsDir, elem...)
+}
+
+// CachePath returns the path under the cache directory of the application, see [CacheDir].
+func CachePath(elem ...string) string {
+	return std.Path(CacheDir, elem...)
+}
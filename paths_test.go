@@ -0,0 +1,63 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaths(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	t.Run("root and work", func(t *testing.T) {
+		e := FromMap(map[string]string{"APP_ROOT": "/opt/app", "APP_WD": "/home/user"})
+		assert.Equal(t, "/opt/app", e.RootPath())
+		assert.Equal(t, "/opt/app/config/app.yaml", e.RootPath("config", "app.yaml"))
+		assert.Equal(t, "/home/user/.env", e.WorkPath(".env"))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		e := New()
+		assert.Equal(t, filepath.Join(wd, ".env"), e.WorkPath(".env"))
+		// the test binary is built by go test, the root is the module root.
+		assert.Equal(t, filepath.Join(wd, "config"), e.RootPath("config"))
+	})
+
+	t.Run("named", func(t *testing.T) {
+		e := FromMap(map[string]string{
+			"APP_ROOT":        "/opt/app",
+			"APP_CONFIG_DIR":  "/etc/app",
+			"APP_CACHE_DIR":   "var/cache",
+			"XDG_CONFIG_HOME": "/xdg/config",
+		})
+		assert.Equal(t, "/etc/app/app.yaml", e.Path(ConfigDir, "app.yaml"))
+		assert.Equal(t, "/opt/app/var/cache", e.Path(CacheDir))
+		assert.Equal(t, "/opt/app/storage/uploads", e.Path(StorageDir, "uploads"))
+		assert.Equal(t, "/opt/app/logs", e.Path(LogsDir))
+		assert.Equal(t, "/opt/app/sessions", e.Path(AppDir{Name: "sessions", Env: "APP_SESSIONS_DIR"}))
+	})
+
+	t.Run("xdg", func(t *testing.T) {
+		e := FromMap(map[string]string{
+			"APP_ROOT":        "/opt/app",
+			"APP_NAME":        "app",
+			"APP_CACHE_DIR":   "/var/cache/app",
+			"HOME":            "/home/user",
+			"XDG_CONFIG_HOME": "/xdg/config",
+			"XDG_DATA_HOME":   "relative",
+		})
+		assert.Equal(t, "/var/cache/app", e.Path(CacheDir))
+		assert.Equal(t, "/xdg/config/app/app.yaml", e.Path(ConfigDir, "app.yaml"))
+		assert.Equal(t, "/opt/app/sessions", e.Path(AppDir{Name: "sessions"}))
+		if runtime.GOOS == "linux" {
+			assert.Equal(t, "/home/user/.local/share/app", e.Path(StorageDir))
+			assert.Equal(t, "/home/user/.local/state/app/app.log", e.Path(LogsDir, "app.log"))
+		} else {
+			assert.Equal(t, "/opt/app/storage", e.Path(StorageDir))
+		}
+	})
+}
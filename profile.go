@@ -0,0 +1,80 @@
+package env
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// ProfileKey is the name of the variable which holds the active profile.
+// It is consulted by LoadProfile and OverrideProfile when no profile is given.
+var ProfileKey = "APP_ENV"
+
+// ProfileFiles returns the dotenv files of the given profile, from the lowest precedence to the highest:
+//   - .env
+//   - .env.{profile}
+//   - .env.local
+//   - .env.{profile}.local
+//
+// The profile specific files are omitted when the profile is empty,
+// and .env.local is omitted for the "test" profile so that tests produce the same results for everyone.
+func ProfileFiles(profile string) []string {
+	filenames := []string{".env"}
+	if profile != "" {
+		filenames = append(filenames, ".env."+profile)
+	}
+	if profile != "test" {
+		filenames = append(filenames, ".env.local")
+	}
+	if profile != "" {
+		filenames = append(filenames, ".env."+profile+".local")
+	}
+	return filenames
+}
+
+// LoadProfile loads the dotenv files of the given profile into the Env, see [ProfileFiles].
+// If the profile is empty, it is read from the variable named by [ProfileKey].
+// Missing files are skipped, variables which are already present are not overridden.
+func (e *Env) LoadProfile(profile string) error {
+	filenames, err := e.profileFiles(profile)
+	if err != nil || len(filenames) == 0 {
+		return err
+	}
+	return e.Load(filenames...)
+}
+
+// OverrideProfile is like LoadProfile but overrides any existing values.
+func (e *Env) OverrideProfile(profile string) error {
+	filenames, err := e.profileFiles(profile)
+	if err != nil || len(filenames) == 0 {
+		return err
+	}
+	return e.Override(filenames...)
+}
+
+func (e *Env) profileFiles(profile string) ([]string, error) {
+	if profile == "" {
+		profile = e.Get(ProfileKey)
+	}
+	var filenames []string
+	for _, filename := range ProfileFiles(profile) {
+		if _, err := os.Stat(filename); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		filenames = append(filenames, filename)
+	}
+	return filenames, nil
+}
+
+// LoadProfile loads the dotenv files of the given profile into the environment.
+func LoadProfile(profile string) error {
+	return std.LoadProfile(profile)
+}
+
+// OverrideProfile loads the dotenv files of the given profile into the environment, overriding any existing values.
+func OverrideProfile(profile string) error {
+	return std.OverrideProfile(profile)
+}
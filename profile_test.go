@@ -0,0 +1,71 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
+
+func TestProfileFiles(t *testing.T) {
+	assert.Equal(t, []string{".env", ".env.local"}, ProfileFiles(""))
+	assert.Equal(t, []string{".env", ".env.production", ".env.local", ".env.production.local"}, ProfileFiles("production"))
+	assert.Equal(t, []string{".env", ".env.test", ".env.test.local"}, ProfileFiles("test"))
+}
+
+func TestLoadProfile(t *testing.T) {
+	chdir(t, "testdata/profile")
+
+	t.Run("without profile", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadProfile(""))
+		assert.Equal(t, "db.local", e.Get("DB_HOST"))
+		assert.Equal(t, "info", e.Get("LOG_LEVEL"))
+	})
+
+	t.Run("with profile", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.LoadProfile("production"))
+		assert.Equal(t, "gopi", e.Get("APP_NAME"))
+		assert.Equal(t, "db.local", e.Get("DB_HOST"))
+		assert.Equal(t, "warn", e.Get("LOG_LEVEL"))
+	})
+
+	t.Run("profile from variable", func(t *testing.T) {
+		e := FromMap(map[string]string{"APP_ENV": "test"})
+		assert.NoError(t, e.LoadProfile(""))
+		assert.Equal(t, "localhost", e.Get("DB_HOST"))
+		assert.Equal(t, "debug", e.Get("LOG_LEVEL"))
+	})
+
+	t.Run("existing values are kept", func(t *testing.T) {
+		e := FromMap(map[string]string{"LOG_LEVEL": "trace"})
+		assert.NoError(t, e.LoadProfile("production"))
+		assert.Equal(t, "trace", e.Get("LOG_LEVEL"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		e := FromMap(map[string]string{"LOG_LEVEL": "trace"})
+		assert.NoError(t, e.OverrideProfile("production"))
+		assert.Equal(t, "warn", e.Get("LOG_LEVEL"))
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		chdir(t, t.TempDir())
+		assert.NoError(t, New().LoadProfile("production"))
+	})
+}
@@ -0,0 +1,282 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Registry holds the declarations of the variables read by an application, in the way the flag package declares flags:
+//
+//	var Port = env.Int("PORT", 8080, "HTTP listen port")
+//
+// It validates them all at once, prints their usage and reports their values.
+// It is safe for concurrent use.
+type Registry struct {
+	src  Source
+	mu   sync.Mutex
+	vars []declared
+	keys map[string]bool
+}
+
+// Vars is the registry of the variables of the environment, the one the package-level declarations such as [Int] use.
+var Vars = NewRegistry(std)
+
+// NewRegistry returns an empty Registry whose variables are read from the source.
+func NewRegistry(src Source) *Registry {
+	return &Registry{src: src, keys: make(map[string]bool)}
+}
+
+// declared is a variable declared in a Registry, whatever its type.
+type declared interface {
+	info() VarInfo
+	check() error
+}
+
+// VarInfo describes a declared variable, see [Registry.Report].
+type VarInfo struct {
+	Key   string
+	Type  string
+	Usage string
+	// Default is the formatted default value, it is empty for the required variables.
+	Default string
+	// Value is the current value, as it is set. It is redacted for the secret variables.
+	Value string
+	// Set reports whether the variable is set.
+	Set      bool
+	Required bool
+	Secret   bool
+}
+
+// Var is a typed variable declared in a [Registry].
+type Var[T any] struct {
+	reg      *Registry
+	key      string
+	usage    string
+	def      T
+	parse    func(s string) (T, error)
+	format   func(value T) string
+	mu       sync.RWMutex
+	required bool
+	secret   bool
+}
+
+// Define declares a variable of type T in the registry, with a default value and a description,
+// converting its value with the parse function. It panics if the key is already declared.
+func Define[T any](r *Registry, key string, defaultValue T, usage string, parse func(s string) (T, error)) *Var[T] {
+	return define(r, key, defaultValue, usage, parse, func(value T) string { return fmt.Sprint(value) })
+}
+
+func define[T any](r *Registry, key string, defaultValue T, usage string, parse func(s string) (T, error), format func(value T) string) *Var[T] {
+	v := &Var[T]{reg: r, key: key, usage: usage, def: defaultValue, parse: parse, format: format}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys[key] {
+		panic(fmt.Sprintf("env: variable %s declared twice", key))
+	}
+	r.keys[key] = true
+	r.vars = append(r.vars, v)
+	return v
+}
+
+// Key returns the name of the variable.
+func (v *Var[T]) Key() string {
+	return v.key
+}
+
+// Required marks the variable as required: it has no default value, and [Registry.Validate] fails when it is not set.
+func (v *Var[T]) Required() *Var[T] {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.required = true
+	return v
+}
+
+// Secret marks the variable as secret: its value is redacted by [Registry.Report].
+func (v *Var[T]) Secret() *Var[T] {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.secret = true
+	return v
+}
+
+// Value returns the value of the variable, or its default value if it is not set.
+// It fails if the value cannot be converted, or if the variable is required and not set.
+func (v *Var[T]) Value() (T, error) {
+	v.mu.RLock()
+	required := v.required
+	v.mu.RUnlock()
+	value, ok, err := lookupErr(v.reg.src, v.key)
+	if err != nil {
+		return v.def, err
+	}
+	if !ok {
+		if required {
+			return v.def, fmt.Errorf("env: %s is required", v.key)
+		}
+		return v.def, nil
+	}
+	parsed, err := v.parse(value)
+	if err != nil {
+		return v.def, fmt.Errorf("env: %s: %w", v.key, err)
+	}
+	return parsed, nil
+}
+
+// Get returns the value of the variable, or its default value if it is not set or cannot be converted.
+// Call [Registry.Validate] at startup to report the variables which cannot.
+func (v *Var[T]) Get() T {
+	value, _ := v.Value()
+	return value
+}
+
+func (v *Var[T]) check() error {
+	_, err := v.Value()
+	return err
+}
+
+func (v *Var[T]) info() VarInfo {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	info := VarInfo{
+		Key:      v.key,
+		Type:     reflect.TypeFor[T]().String(),
+		Usage:    v.usage,
+		Required: v.required,
+		Secret:   v.secret,
+	}
+	if !v.required {
+		info.Default = v.format(v.def)
+	}
+	info.Value, info.Set = v.reg.src.Lookup(v.key)
+	if info.Set && v.secret {
+		info.Value = "******"
+	}
+	return info
+}
+
+func (r *Registry) declared() []declared {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]declared(nil), r.vars...)
+}
+
+// Validate checks that every required variable is set and that every value set can be converted.
+// It returns all the errors joined.
+func (r *Registry) Validate() error {
+	var errs []error
+	for _, v := range r.declared() {
+		errs = append(errs, v.check())
+	}
+	return errors.Join(errs...)
+}
+
+// Report describes the declared variables, in the order of their declarations.
+func (r *Registry) Report() []VarInfo {
+	vars := r.declared()
+	infos := make([]VarInfo, len(vars))
+	for i, v := range vars {
+		infos[i] = v.info()
+	}
+	return infos
+}
+
+// PrintUsage writes a table of the declared variables, with their types, default values and descriptions.
+func (r *Registry) PrintUsage(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIABLE\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, info := range r.Report() {
+		def := info.Default
+		if info.Required {
+			def = "(required)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", info.Key, info.Type, def, info.Usage)
+	}
+	return tw.Flush()
+}
+
+// String declares a string variable in the registry.
+func (r *Registry) String(key string, defaultValue string, usage string) *Var[string] {
+	return Define(r, key, defaultValue, usage, func(s string) (string, error) { return s, nil })
+}
+
+// Int declares an int variable in the registry.
+func (r *Registry) Int(key string, defaultValue int, usage string) *Var[int] {
+	return Define(r, key, defaultValue, usage, strconv.Atoi)
+}
+
+// Int64 declares an int64 variable in the registry.
+func (r *Registry) Int64(key string, defaultValue int64, usage string) *Var[int64] {
+	return Define(r, key, defaultValue, usage, parseInt64)
+}
+
+// Uint64 declares a uint64 variable in the registry.
+func (r *Registry) Uint64(key string, defaultValue uint64, usage string) *Var[uint64] {
+	return Define(r, key, defaultValue, usage, parseUint64)
+}
+
+// Float64 declares a float64 variable in the registry.
+func (r *Registry) Float64(key string, defaultValue float64, usage string) *Var[float64] {
+	return Define(r, key, defaultValue, usage, parseFloat64)
+}
+
+// Bool declares a bool variable in the registry.
+func (r *Registry) Bool(key string, defaultValue bool, usage string) *Var[bool] {
+	return Define(r, key, defaultValue, usage, strconv.ParseBool)
+}
+
+// Duration declares a time.Duration variable in the registry, see [time.ParseDuration].
+func (r *Registry) Duration(key string, defaultValue time.Duration, usage string) *Var[time.Duration] {
+	return Define(r, key, defaultValue, usage, time.ParseDuration)
+}
+
+// Strings declares a comma-separated []string variable in the registry.
+func (r *Registry) Strings(key string, defaultValue []string, usage string) *Var[[]string] {
+	return define(r, key, defaultValue, usage, splitStrings, func(value []string) string { return strings.Join(value, ",") })
+}
+
+// String declares a string variable in [Vars].
+func String(key string, defaultValue string, usage string) *Var[string] {
+	return Vars.String(key, defaultValue, usage)
+}
+
+// Int declares an int variable in [Vars].
+func Int(key string, defaultValue int, usage string) *Var[int] {
+	return Vars.Int(key, defaultValue, usage)
+}
+
+// Int64 declares an int64 variable in [Vars].
+func Int64(key string, defaultValue int64, usage string) *Var[int64] {
+	return Vars.Int64(key, defaultValue, usage)
+}
+
+// Uint64 declares a uint64 variable in [Vars].
+func Uint64(key string, defaultValue uint64, usage string) *Var[uint64] {
+	return Vars.Uint64(key, defaultValue, usage)
+}
+
+// Float64 declares a float64 variable in [Vars].
+func Float64(key string, defaultValue float64, usage string) *Var[float64] {
+	return Vars.Float64(key, defaultValue, usage)
+}
+
+// Bool declares a bool variable in [Vars].
+func Bool(key string, defaultValue bool, usage string) *Var[bool] {
+	return Vars.Bool(key, defaultValue, usage)
+}
+
+// Duration declares a time.Duration variable in [Vars].
+func Duration(key string, defaultValue time.Duration, usage string) *Var[time.Duration] {
+	return Vars.Duration(key, defaultValue, usage)
+}
+
+// Strings declares a comma-separated []string variable in [Vars].
+func Strings(key string, defaultValue []string, usage string) *Var[[]string] {
+	return Vars.Strings(key, defaultValue, usage)
+}
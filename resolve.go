@@ -0,0 +1,242 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver resolves the references to secrets held by values, such as secret://vault/db#password.
+// It is registered for the scheme of the references it resolves, see [Resolvers.Register].
+type Resolver interface {
+	// Resolve returns the value the reference refers to. The reference is the whole value, scheme included.
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ResolverFunc is a function implementing [Resolver].
+type ResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve implements [Resolver].
+func (f ResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// ResolverOptions configures [Resolvers].
+type ResolverOptions struct {
+	// Timeout is the maximum duration of a resolution, 10 seconds by default.
+	Timeout time.Duration
+	// CacheTTL is how long the resolved values are cached. They are not cached when it is zero.
+	// The errors are never cached.
+	CacheTTL time.Duration
+}
+
+// Resolvers resolves the values which are references to secrets with the resolver registered for their scheme.
+// Values whose scheme has no resolver are kept as they are.
+//
+// A Resolvers resolves the values of an Env when it is attached to it, see [Env.UseResolvers],
+// and the loaded values, see LoadOptions.Resolvers. It is safe for concurrent use.
+type Resolvers struct {
+	opts      ResolverOptions
+	mu        sync.Mutex
+	resolvers map[string]Resolver
+	cache     map[string]cachedValue
+}
+
+type cachedValue struct {
+	value   string
+	expires time.Time
+}
+
+// NewResolvers returns a Resolvers configured by opts, without any resolver registered.
+// Ordinary values may look like references, such as the SQLite DSN file:app.db?cache=shared,
+// so only the schemes of the references in use must be registered.
+func NewResolvers(opts ResolverOptions) *Resolvers {
+	return &Resolvers{
+		opts:      opts,
+		resolvers: make(map[string]Resolver),
+		cache:     make(map[string]cachedValue),
+	}
+}
+
+// Register registers the resolver for the scheme, replacing the one already registered if any.
+// A nil resolver unregisters the scheme.
+func (r *Resolvers) Register(scheme string, resolver Resolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scheme = strings.ToLower(scheme)
+	if resolver == nil {
+		delete(r.resolvers, scheme)
+	} else {
+		r.resolvers[scheme] = resolver
+	}
+}
+
+// Resolve returns the value the reference refers to, or the value itself when it is not a reference
+// to a registered scheme.
+func (r *Resolvers) Resolve(ctx context.Context, value string) (string, error) {
+	value, err := r.resolve(ctx, value)
+	if err != nil {
+		return "", fmt.Errorf("env: %w", err)
+	}
+	return value, nil
+}
+
+func (r *Resolvers) resolve(ctx context.Context, value string) (string, error) {
+	scheme, ok := schemeOf(value)
+	if !ok {
+		return value, nil
+	}
+	r.mu.Lock()
+	resolver, ok := r.resolvers[scheme]
+	cached, isCached := r.cache[value]
+	r.mu.Unlock()
+	if !ok {
+		return value, nil
+	}
+	if isCached && time.Now().Before(cached.expires) {
+		return cached.value, nil
+	}
+	timeout := r.opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resolved, err := resolver.Resolve(ctx, value)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", redact(value), err)
+	}
+	if r.opts.CacheTTL > 0 {
+		r.mu.Lock()
+		r.cache[value] = cachedValue{value: resolved, expires: time.Now().Add(r.opts.CacheTTL)}
+		r.mu.Unlock()
+	}
+	return resolved, nil
+}
+
+// ClearCache removes the cached values.
+func (r *Resolvers) ClearCache() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.cache)
+}
+
+// schemeOf returns the lower-cased scheme of a value which looks like a URL.
+func schemeOf(value string) (string, bool) {
+	i := strings.IndexByte(value, ':')
+	if i <= 0 {
+		return "", false
+	}
+	for j := 0; j < i; j++ {
+		c := value[j]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || j > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.')) {
+			return "", false
+		}
+	}
+	return strings.ToLower(value[:i]), true
+}
+
+// redact removes the user information of a reference, which may hold credentials.
+func redact(ref string) string {
+	if u, err := url.Parse(ref); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return ref
+}
+
+// FileResolver resolves file:// URLs with an absolute path, such as file:///run/secrets/db, to the content of the file
+// without its trailing newlines. It is not registered by default:
+//
+//	r := env.NewResolvers(env.ResolverOptions{})
+//	r.Register("file", env.FileResolver{})
+type FileResolver struct {
+	// MaxSize is the maximum size of a file in bytes, 64 KiB by default.
+	MaxSize int64
+}
+
+// Resolve implements [Resolver].
+func (f FileResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", errors.New("remote files are not supported")
+	}
+	if u.Opaque != "" || !strings.HasPrefix(ref[len(u.Scheme):], "://") || !strings.HasPrefix(u.Path, "/") {
+		return "", errors.New("not a file:// URL with an absolute path")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", errors.New("file URLs cannot have a query or a fragment")
+	}
+	maxSize := f.MaxSize
+	if maxSize <= 0 {
+		maxSize = 64 << 10
+	}
+	return readSecretFile(filepath.FromSlash(u.Path), maxSize)
+}
+
+// ExecResolver resolves exec: references, such as exec:vault kv get -field=password secret/db,
+// to the standard output of the command without its trailing newlines.
+// The command is split on white spaces and run without a shell.
+//
+// It runs arbitrary commands, so it is not registered by default and must only be used with trusted values:
+//
+//	r := env.NewResolvers(env.ResolverOptions{})
+//	r.Register("exec", env.ExecResolver{})
+type ExecResolver struct{}
+
+// Resolve implements [Resolver].
+func (ExecResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	_, command, _ := strings.Cut(ref, ":")
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// UseResolvers enables the resolution of the values which are references to secrets by r,
+// or disables it when r is nil. The lookups of the Env resolve the references then, see [Env.Lookup].
+func (e *Env) UseResolvers(r *Resolvers) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resolvers = r
+}
+
+// UseResolvers enables the resolution of the references to secrets of the environment, see [Env.UseResolvers].
+func UseResolvers(r *Resolvers) {
+	std.UseResolvers(r)
+}
+
+// resolveValue resolves the value of the variable named by the key when the resolvers are enabled.
+func (e *Env) resolveValue(key, value string) (string, bool, error) {
+	e.mu.RLock()
+	r := e.resolvers
+	e.mu.RUnlock()
+	if r == nil {
+		return value, true, nil
+	}
+	value, err := r.resolve(context.Background(), value)
+	if err != nil {
+		return "", false, fmt.Errorf("env: %s: %w", key, err)
+	}
+	return value, true, nil
+}
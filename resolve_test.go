@@ -0,0 +1,203 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vaultResolver resolves secret://mount/path#field references with a key-value store served over HTTP.
+type vaultResolver struct {
+	addr string
+}
+
+func (v vaultResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+u.Host+u.Path, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var data map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", err
+	}
+	value, ok := data[u.Fragment]
+	if !ok {
+		return "", fmt.Errorf("no field %q", u.Fragment)
+	}
+	return value, nil
+}
+
+func TestResolvers(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/v1/vault/db":
+			_ = json.NewEncoder(w).Encode(map[string]string{"user": "root", "password": "s3cr3t"})
+		case "/v1/vault/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	vault := vaultResolver{addr: server.URL}
+
+	dir := t.TempDir()
+	password := filepath.Join(dir, "password")
+	assert.NoError(t, os.WriteFile(password, []byte("f1l3\n"), 0o600))
+
+	t.Run("resolve", func(t *testing.T) {
+		r := NewResolvers(ResolverOptions{})
+		r.Register("secret", vault)
+		r.Register("file", FileResolver{})
+		ctx := context.Background()
+		for value, want := range map[string]string{
+			"secret://vault/db#password": "s3cr3t",
+			"SECRET://vault/db#user":     "root",
+			"file://" + password:         "f1l3",
+			"https://example.com":        "https://example.com",
+			"exec:echo hello":            "exec:echo hello",
+			"plain":                      "plain",
+			"C:\\secrets":                "C:\\secrets",
+			"":                           "",
+		} {
+			got, err := r.Resolve(ctx, value)
+			assert.NoError(t, err, value)
+			assert.Equal(t, want, got, value)
+		}
+
+		_, err := r.Resolve(ctx, "secret://vault/missing#password")
+		assert.EqualError(t, err, "env: resolve secret://vault/missing#password: status 404")
+		_, err = r.Resolve(ctx, "file://remote/password")
+		assert.EqualError(t, err, "env: resolve file://remote/password: remote files are not supported")
+		_, err = r.Resolve(ctx, "file:password")
+		assert.EqualError(t, err, "env: resolve file:password: not a file:// URL with an absolute path")
+		_, err = r.Resolve(ctx, "file://"+password+"?mode=ro")
+		assert.EqualError(t, err, "env: resolve file://"+password+"?mode=ro: file URLs cannot have a query or a fragment")
+
+		r.Register("secret", nil)
+		got, err := r.Resolve(ctx, "secret://vault/db#password")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret://vault/db#password", got)
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		r := NewResolvers(ResolverOptions{})
+		for _, value := range []string{"file:app.db?cache=shared", "file://" + password, "exec:echo hello"} {
+			got, err := r.Resolve(context.Background(), value)
+			assert.NoError(t, err)
+			assert.Equal(t, value, got)
+		}
+		e := FromMap(map[string]string{"DATABASE_URL": "file:app.db?cache=shared"})
+		e.UseResolvers(r)
+		assert.Equal(t, "file:app.db?cache=shared", e.Get("DATABASE_URL"))
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		r := NewResolvers(ResolverOptions{CacheTTL: time.Minute})
+		r.Register("secret", vault)
+		hits.Store(0)
+		for range 3 {
+			got, err := r.Resolve(context.Background(), "secret://vault/db#password")
+			assert.NoError(t, err)
+			assert.Equal(t, "s3cr3t", got)
+		}
+		assert.Equal(t, int32(1), hits.Load())
+		r.ClearCache()
+		_, err := r.Resolve(context.Background(), "secret://vault/db#password")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), hits.Load())
+
+		r = NewResolvers(ResolverOptions{})
+		r.Register("secret", vault)
+		hits.Store(0)
+		for range 2 {
+			_, err := r.Resolve(context.Background(), "secret://vault/db#password")
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), hits.Load())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		r := NewResolvers(ResolverOptions{Timeout: 10 * time.Millisecond})
+		r.Register("secret", vault)
+		_, err := r.Resolve(context.Background(), "secret://vault/slow#password")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		r := NewResolvers(ResolverOptions{})
+		r.Register("exec", ExecResolver{})
+		got, err := r.Resolve(context.Background(), "exec:echo hello world")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", got)
+		_, err = r.Resolve(context.Background(), "exec:")
+		assert.EqualError(t, err, "env: resolve exec:: empty command")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		r := NewResolvers(ResolverOptions{})
+		r.Register("secret", vault)
+		r.Register("file", FileResolver{})
+		e := FromMap(map[string]string{
+			"DB_PASSWORD": "secret://vault/db#password",
+			"DB_USER":     "secret://vault/db#user",
+			"DB_MISSING":  "secret://vault/db#missing",
+			"API_KEY":     "file://" + password,
+		})
+		assert.Equal(t, "secret://vault/db#password", e.Get("DB_PASSWORD"))
+		e.UseResolvers(r)
+		assert.Equal(t, "s3cr3t", e.Get("DB_PASSWORD"))
+		assert.Equal(t, "f1l3", e.Get("API_KEY"))
+		assert.Equal(t, "root:s3cr3t", e.Expand("${DB_USER}:${DB_PASSWORD}"))
+		_, ok := e.Lookup("DB_MISSING")
+		assert.False(t, ok)
+		_, err := GetTFrom(e, "DB_MISSING", func(s string) (string, error) { return s, nil })
+		assert.EqualError(t, err, `env: DB_MISSING: resolve secret://vault/db#missing: no field "missing"`)
+	})
+
+	t.Run("load", func(t *testing.T) {
+		r := NewResolvers(ResolverOptions{})
+		r.Register("secret", vault)
+		filename := filepath.Join(dir, ".env")
+		assert.NoError(t, os.WriteFile(filename, []byte("MOUNT=vault\nDB_PASSWORD=secret://${MOUNT}/db#password\n"), 0o600))
+
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Expand: true, Resolvers: r}, filename))
+		assert.Equal(t, "s3cr3t", e.Get("DB_PASSWORD"))
+
+		e = New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Expand: true}, filename))
+		assert.Equal(t, "secret://vault/db#password", e.Get("DB_PASSWORD"))
+
+		assert.NoError(t, os.WriteFile(filename, []byte("DB_PASSWORD=secret://vault/missing#password\n"), 0o600))
+		err := New().LoadWithOptions(LoadOptions{Resolvers: r}, filename)
+		assert.EqualError(t, err, "env: DB_PASSWORD: resolve secret://vault/missing#password: status 404")
+	})
+}
@@ -0,0 +1,133 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SearchOptions configures the search of files in the parent directories.
+type SearchOptions struct {
+	// Dir is the directory the search starts from, the value of APP_WD by default, or the working directory.
+	Dir string
+	// Markers are the files or directories marking the directory where the search stops, such as the root of a repository.
+	// It is go.mod and .git when nil. Set it to an empty slice to search up to the root of the file system.
+	Markers []string
+}
+
+// Search looks for the named files from the starting directory upward, and returns the paths of the files found
+// in the first directory holding any of them, in the order of the names. If no file is named, it looks for ".env".
+// The search stops at the first directory holding one of the markers, after looking for the files there.
+// It returns an error wrapping [fs.ErrNotExist] when no file is found.
+func (e *Env) Search(opts SearchOptions, names ...string) ([]string, error) {
+	if len(names) == 0 {
+		names = []string{".env"}
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = e.Get("APP_WD")
+	}
+	markers := opts.Markers
+	if markers == nil {
+		markers = []string{"go.mod", ".git"}
+	}
+	return search(dir, markers, names)
+}
+
+// search looks for the named files from the directory upward, the working directory if it is empty,
+// stopping at the first directory holding one of the markers, see [Env.Search].
+func search(dir string, markers, names []string) ([]string, error) {
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir = wd
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	start := dir
+	for {
+		var paths []string
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			ok, err := exists(path)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			return paths, nil
+		}
+		for _, marker := range markers {
+			ok, err := exists(filepath.Join(dir, marker))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return nil, notFound(names, start, dir)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, notFound(names, start, dir)
+		}
+		dir = parent
+	}
+}
+
+// LoadUp looks for the named file(s) from APP_WD upward and loads them into the Env, see [Env.Search].
+// It returns the paths of the loaded files. Variables which are already present are not overridden.
+func (e *Env) LoadUp(names ...string) ([]string, error) {
+	paths, err := e.Search(SearchOptions{}, names...)
+	if err != nil {
+		return nil, err
+	}
+	return paths, e.Load(paths...)
+}
+
+// OverrideUp is like LoadUp but overrides any existing values.
+func (e *Env) OverrideUp(names ...string) ([]string, error) {
+	paths, err := e.Search(SearchOptions{}, names...)
+	if err != nil {
+		return nil, err
+	}
+	return paths, e.Override(paths...)
+}
+
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func notFound(names []string, start, stop string) error {
+	return fmt.Errorf("env: %s not found from %s up to %s: %w", strings.Join(names, ", "), start, stop, fs.ErrNotExist)
+}
+
+// Search looks for the named files from APP_WD upward, see [Env.Search].
+func Search(opts SearchOptions, names ...string) ([]string, error) {
+	return std.Search(opts, names...)
+}
+
+// LoadUp looks for the named file(s) from APP_WD upward and loads them into the environment.
+// It returns the paths of the loaded files.
+func LoadUp(names ...string) ([]string, error) {
+	return std.LoadUp(names...)
+}
+
+// OverrideUp looks for the named file(s) from APP_WD upward and loads them into the environment,
+// overriding any existing values. It returns the paths of the loaded files.
+func OverrideUp(names ...string) ([]string, error) {
+	return std.OverrideUp(names...)
+}
@@ -0,0 +1,62 @@
+package env
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	module := filepath.Join(root, "module")
+	nested := filepath.Join(module, "cmd", "app")
+	assert.NoError(t, os.MkdirAll(nested, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, ".env.above"), []byte("A=0\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(module, "go.mod"), []byte("module example.com/app\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(module, ".env"), []byte("A=1\nB=2\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(module, ".env.local"), []byte("B=3\n"), 0o600))
+
+	t.Run("upward", func(t *testing.T) {
+		e := New()
+		paths, err := e.Search(SearchOptions{Dir: nested})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(module, ".env")}, paths)
+		paths, err = e.Search(SearchOptions{Dir: nested}, ".env", ".env.missing", ".env.local")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(module, ".env"), filepath.Join(module, ".env.local")}, paths)
+	})
+
+	t.Run("stops at markers", func(t *testing.T) {
+		_, err := New().Search(SearchOptions{Dir: nested}, ".env.above")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		assert.Contains(t, err.Error(), "up to "+module)
+		paths, err := New().Search(SearchOptions{Dir: nested, Markers: []string{}}, ".env.above")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(root, ".env.above")}, paths)
+	})
+
+	t.Run("load from APP_WD", func(t *testing.T) {
+		e := FromMap(map[string]string{"APP_WD": nested})
+		paths, err := e.LoadUp(".env", ".env.local")
+		assert.NoError(t, err)
+		assert.Len(t, paths, 2)
+		assert.Equal(t, "1", e.Get("A"))
+		assert.Equal(t, "3", e.Get("B"))
+
+		e = FromMap(map[string]string{"APP_WD": nested, "A": "0"})
+		_, err = e.OverrideUp()
+		assert.NoError(t, err)
+		assert.Equal(t, "1", e.Get("A"))
+	})
+
+	t.Run("working directory", func(t *testing.T) {
+		chdir(t, nested)
+		paths, err := New().Search(SearchOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, paths, 1)
+		assert.Equal(t, ".env", filepath.Base(paths[0]))
+	})
+}
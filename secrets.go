@@ -0,0 +1,145 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FileSecretOptions configures the resolution of the KEY_FILE variables,
+// the convention of the container images reading secrets from files, such as POSTGRES_PASSWORD_FILE.
+//
+// When KEY is not set and KEY_FILE is, the value of KEY is the content of the file named by KEY_FILE,
+// without its trailing newlines. Setting both KEY and KEY_FILE is an error.
+type FileSecretOptions struct {
+	// Suffix is the suffix of the variables naming the secret files, "_FILE" by default.
+	Suffix string
+	// MaxSize is the maximum size of a secret file in bytes, 64 KiB by default.
+	MaxSize int64
+}
+
+func (opts *FileSecretOptions) suffix() string {
+	if opts.Suffix == "" {
+		return "_FILE"
+	}
+	return opts.Suffix
+}
+
+func (opts *FileSecretOptions) maxSize() int64 {
+	if opts.MaxSize <= 0 {
+		return 64 << 10
+	}
+	return opts.MaxSize
+}
+
+// UseFileSecrets enables the resolution of the KEY_FILE variables as configured by opts,
+// or disables it when opts is nil. The lookups of the Env resolve the secret files then, see [Env.Lookup].
+func (e *Env) UseFileSecrets(opts *FileSecretOptions) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.secrets = opts
+}
+
+// UseFileSecrets enables the resolution of the KEY_FILE variables of the environment, see [Env.UseFileSecrets].
+func UseFileSecrets(opts *FileSecretOptions) {
+	std.UseFileSecrets(opts)
+}
+
+func (e *Env) fileSecrets() *FileSecretOptions {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.secrets
+}
+
+// lookupErr returns the value of the variable named by the key, resolving the secret files
+// and the references to secrets when they are enabled.
+func (e *Env) lookupErr(key string) (string, bool, error) {
+	value, ok, err := e.lookupFileSecret(key)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+	return e.resolveValue(key, value)
+}
+
+// lookupFileSecret returns the value of the variable named by the key, resolving the secret files when they are enabled.
+func (e *Env) lookupFileSecret(key string) (string, bool, error) {
+	value, ok := e.lookupValue(key)
+	opts := e.fileSecrets()
+	if opts == nil || strings.HasSuffix(key, opts.suffix()) {
+		return value, ok, nil
+	}
+	filename, isFile := e.lookupValue(key + opts.suffix())
+	if !isFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("env: both %s and %s are set", key, key+opts.suffix())
+	}
+	value, err := readSecretFile(filename, opts.maxSize())
+	if err != nil {
+		return "", false, fmt.Errorf("env: %s: %w", key+opts.suffix(), err)
+	}
+	return value, true, nil
+}
+
+// lookupValue returns the value of the variable named by the key, reporting an empty value as not present
+// when the Env treats them as unset.
+func (e *Env) lookupValue(key string) (string, bool) {
+	value, ok := e.lookup(key)
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if ok && value == "" && e.emptyUnset {
+		return "", false
+	}
+	return value, ok
+}
+
+// readSecretFile returns the content of the named secret file without its trailing newlines.
+func readSecretFile(filename string, maxSize int64) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > maxSize {
+		return "", fmt.Errorf("secret file %s exceeds %d bytes", filename, maxSize)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveFileSecrets returns the batch with its KEY_FILE variables replaced by the variables they resolve,
+// and sets their values. The KEY_FILE variables are not loaded themselves, so that the Env does not hold
+// both KEY and KEY_FILE when the lookups resolve the secret files too. The values of the variables
+// which are kept as they are present are not resolved.
+func resolveFileSecrets(batch []entry, values map[string]string, kept func(key string) bool, opts *FileSecretOptions) ([]entry, error) {
+	defined := make(map[string]bool, len(batch))
+	for _, ent := range batch {
+		defined[ent.key] = true
+	}
+	resolved := make([]entry, 0, len(batch))
+	for _, ent := range batch {
+		key, ok := strings.CutSuffix(ent.key, opts.suffix())
+		if !ok || key == "" {
+			resolved = append(resolved, ent)
+			continue
+		}
+		if defined[key] {
+			return nil, fmt.Errorf("env: both %s and %s are set", key, ent.key)
+		}
+		if kept(key) {
+			continue
+		}
+		value, err := readSecretFile(values[ent.key], opts.maxSize())
+		if err != nil {
+			return nil, fmt.Errorf("env: %s: %w", ent.key, err)
+		}
+		values[key] = value
+		resolved = append(resolved, entry{key: key, value: value, literal: true, line: ent.line, filename: ent.filename})
+	}
+	return resolved, nil
+}
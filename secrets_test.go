@@ -0,0 +1,101 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSecrets(t *testing.T) {
+	dir := t.TempDir()
+	password := filepath.Join(dir, "password")
+	port := filepath.Join(dir, "port")
+	large := filepath.Join(dir, "large")
+	assert.NoError(t, os.WriteFile(password, []byte("s3cr3t\n"), 0o600))
+	assert.NoError(t, os.WriteFile(port, []byte("5432\r\n"), 0o600))
+	assert.NoError(t, os.WriteFile(large, []byte(strings.Repeat("x", 100)), 0o600))
+
+	t.Run("disabled", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_PASSWORD_FILE": password})
+		_, ok := e.Lookup("DB_PASSWORD")
+		assert.False(t, ok)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_PASSWORD_FILE": password, "DB_PORT_FILE": port, "DB_USER": "root"})
+		e.UseFileSecrets(&FileSecretOptions{})
+		assert.Equal(t, "s3cr3t", e.Get("DB_PASSWORD"))
+		assert.Equal(t, "root", e.Get("DB_USER"))
+		assert.Equal(t, password, e.Get("DB_PASSWORD_FILE"))
+		assert.Equal(t, 5432, e.MustGetInt("DB_PORT"))
+		assert.Equal(t, "s3cr3t@5432", e.Expand("${DB_PASSWORD}@${DB_PORT}"))
+		assert.ElementsMatch(t, []string{"DB_PASSWORD_FILE", "DB_PORT_FILE", "DB_USER", "DB_PASSWORD", "DB_PORT"}, e.Keys())
+
+		type config struct {
+			Password string `env:"DB_PASSWORD"`
+			Port     int    `env:"DB_PORT"`
+		}
+		cfg, err := UnmarshalFrom[config](e)
+		assert.NoError(t, err)
+		assert.Equal(t, config{Password: "s3cr3t", Port: 5432}, cfg)
+
+		e.UseFileSecrets(nil)
+		assert.Equal(t, "", e.Get("DB_PASSWORD"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		e := FromMap(map[string]string{
+			"BOTH":         "1",
+			"BOTH_FILE":    password,
+			"MISSING_FILE": filepath.Join(dir, "missing"),
+			"LARGE_FILE":   large,
+		})
+		e.UseFileSecrets(&FileSecretOptions{MaxSize: 10})
+		_, ok := e.Lookup("BOTH")
+		assert.False(t, ok)
+		_, err := e.GetInt("BOTH")
+		assert.EqualError(t, err, "env: both BOTH and BOTH_FILE are set")
+		_, err = e.GetIntOr("MISSING", 1)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = GetTFrom(e, "LARGE", func(s string) (string, error) { return s, nil })
+		assert.EqualError(t, err, "env: LARGE_FILE: secret file "+large+" exceeds 10 bytes")
+		_, err = UnmarshalFrom[struct{}](e)
+		assert.Error(t, err)
+	})
+
+	t.Run("suffix", func(t *testing.T) {
+		e := FromMap(map[string]string{"DB_PASSWORD__PATH": password})
+		e.UseFileSecrets(&FileSecretOptions{Suffix: "__PATH"})
+		assert.Equal(t, "s3cr3t", e.Get("DB_PASSWORD"))
+	})
+
+	t.Run("load", func(t *testing.T) {
+		filename := filepath.Join(dir, ".env")
+		assert.NoError(t, os.WriteFile(filename, []byte("SECRETS="+dir+"\nDB_PASSWORD_FILE=${SECRETS}/password\n"), 0o600))
+		e := New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Expand: true, FileSecrets: &FileSecretOptions{}}, filename))
+		assert.Equal(t, "s3cr3t", e.Get("DB_PASSWORD"))
+		assert.ElementsMatch(t, []string{"SECRETS", "DB_PASSWORD"}, e.Keys())
+
+		e.UseFileSecrets(&FileSecretOptions{})
+		value, ok := e.Lookup("DB_PASSWORD")
+		assert.True(t, ok)
+		assert.Equal(t, "s3cr3t", value)
+		s, err := GetTFrom(e, "DB_PASSWORD", func(s string) (string, error) { return s, nil })
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cr3t", s)
+
+		e = FromMap(map[string]string{"DB_PASSWORD": "existing"})
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{Expand: true, FileSecrets: &FileSecretOptions{}}, filename))
+		assert.Equal(t, "existing", e.Get("DB_PASSWORD"))
+		e.UseFileSecrets(&FileSecretOptions{})
+		assert.Equal(t, "existing", e.Get("DB_PASSWORD"))
+
+		assert.NoError(t, os.WriteFile(filename, []byte("DB_PASSWORD=1\nDB_PASSWORD_FILE="+password+"\n"), 0o600))
+		err = New().LoadWithOptions(LoadOptions{FileSecrets: &FileSecretOptions{}}, filename)
+		assert.EqualError(t, err, "env: both DB_PASSWORD and DB_PASSWORD_FILE are set")
+	})
+}
@@ -0,0 +1,218 @@
+package env
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// shell expands a reference written with the shell syntax.
+func (x *expansion) shell(ref *reference) string {
+	value, ok := x.lookup(ref.name)
+	if ok {
+		value = x.recurse(ref.name, value)
+	}
+	// the operators with a colon also treat empty values as missing
+	missing := !ok || value == "" && strings.HasPrefix(ref.op, ":")
+	switch ref.op {
+	case "-", ":-":
+		if missing {
+			return x.eval(ref.word)
+		}
+		return value
+	case "=", ":=":
+		if missing {
+			word := x.eval(ref.word)
+			if x.assign != nil {
+				if err := x.assign(ref.name, word); err != nil {
+					x.errs = append(x.errs, err)
+				}
+			}
+			return word
+		}
+		return value
+	case "?", ":?":
+		if missing {
+			message := x.eval(ref.word)
+			if message == "" && ref.op == "?" {
+				message = "parameter not set"
+			} else if message == "" {
+				message = "parameter null or not set"
+			}
+			line, column := position(x.src, ref.offset)
+			x.errs = append(x.errs, &ParameterError{Name: ref.name, Message: message, Offset: ref.offset, Line: line, Column: column})
+		}
+		return value
+	case "+", ":+":
+		if missing {
+			return ""
+		}
+		return x.eval(ref.word)
+	}
+	if !ok {
+		x.undefinedAt(ref.offset, ref.name)
+		return ""
+	}
+	switch {
+	case ref.isLength:
+		return strconv.Itoa(utf8.RuneCountInString(value))
+	case ref.op == ":":
+		return substring(value, ref.start, ref.length, ref.withLength)
+	case ref.op == "#":
+		return trimPattern(value, x.eval(ref.word), true, false)
+	case ref.op == "##":
+		return trimPattern(value, x.eval(ref.word), true, true)
+	case ref.op == "%":
+		return trimPattern(value, x.eval(ref.word), false, false)
+	case ref.op == "%%":
+		return trimPattern(value, x.eval(ref.word), false, true)
+	case ref.op == "^^":
+		return strings.ToUpper(value)
+	case ref.op == ",,":
+		return strings.ToLower(value)
+	case ref.op == "^":
+		return mapFirstRune(value, unicode.ToUpper)
+	case ref.op == ",":
+		return mapFirstRune(value, unicode.ToLower)
+	}
+	return value
+}
+
+// substring returns the characters of s between offset and offset+length.
+// A negative offset counts from the end of s, and so does a negative length.
+func substring(s string, offset, length int, withLength bool) string {
+	runes := []rune(s)
+	if offset < 0 {
+		offset += len(runes)
+		if offset < 0 {
+			return ""
+		}
+	}
+	if offset > len(runes) {
+		return ""
+	}
+	end := len(runes)
+	if withLength {
+		if length < 0 {
+			end += length
+		} else if offset+length < end {
+			end = offset + length
+		}
+	}
+	if end < offset {
+		return ""
+	}
+	return string(runes[offset:end])
+}
+
+// trimPattern removes the shortest, or longest, prefix or suffix of s matching the pattern.
+func trimPattern(s, pattern string, prefix, longest bool) string {
+	// the shortest prefix and the longest suffix are found by moving the cut forward
+	forward := prefix != longest
+	for n := 0; n <= len(s); n++ {
+		i := n
+		if !forward {
+			i = len(s) - n
+		}
+		if i < len(s) && !utf8.RuneStart(s[i]) {
+			continue
+		}
+		if prefix && matchPattern(pattern, s[:i]) {
+			return s[i:]
+		}
+		if !prefix && matchPattern(pattern, s[i:]) {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// matchPattern reports whether s matches the shell pattern, which supports
+// the * and ? wildcards, [...] bracket expressions and \ escapes.
+// On a mismatch, it only backtracks to the last star, which makes it O(len(s)*len(pattern)).
+func matchPattern(pattern, s string) bool {
+	p, i := 0, 0
+	// the position in the pattern after the last star, and the position in s it matches from
+	star, next := -1, 0
+	for p < len(pattern) || i < len(s) {
+		if p < len(pattern) {
+			if pattern[p] == '*' {
+				p++
+				star, next = p, i
+				continue
+			}
+			if pw, sw, ok := matchChar(pattern[p:], s[i:]); ok {
+				p, i = p+pw, i+sw
+				continue
+			}
+		}
+		if star < 0 || next == len(s) {
+			return false
+		}
+		_, n := utf8.DecodeRuneInString(s[next:])
+		next += n
+		p, i = star, next
+	}
+	return true
+}
+
+// matchChar matches the beginning of s against the element at the beginning of the pattern, which is not a star,
+// and returns the widths of the element and of the matched character.
+func matchChar(pattern, s string) (pw, sw int, ok bool) {
+	if s == "" {
+		return 0, 0, false
+	}
+	switch pattern[0] {
+	case '?':
+		_, n := utf8.DecodeRuneInString(s)
+		return 1, n, true
+	case '[':
+		r, n := utf8.DecodeRuneInString(s)
+		matched, width, ok := matchBracket(pattern, r)
+		if !ok {
+			// an unterminated bracket matches itself
+			return 1, 1, s[0] == '['
+		}
+		return width, n, matched
+	case '\\':
+		if len(pattern) > 1 {
+			return 2, 1, s[0] == pattern[1]
+		}
+	}
+	return 1, 1, s[0] == pattern[0]
+}
+
+// matchBracket matches r against the bracket expression at the beginning of pattern,
+// and returns the width of the expression, or false if it is not terminated.
+func matchBracket(pattern string, r rune) (matched bool, width int, ok bool) {
+	i := 1
+	negate := i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^')
+	if negate {
+		i++
+	}
+	for first := true; i < len(pattern); first = false {
+		if pattern[i] == ']' && !first {
+			return matched != negate, i + 1, true
+		}
+		lo, n := utf8.DecodeRuneInString(pattern[i:])
+		i += n
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi, n = utf8.DecodeRuneInString(pattern[i+1:])
+			i += 1 + n
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+	return false, 0, false
+}
+
+func mapFirstRune(s string, mapping func(rune) rune) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 {
+		return s
+	}
+	return string(mapping(r)) + s[n:]
+}
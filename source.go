@@ -0,0 +1,165 @@
+package env
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Source is a read-only set of variables.
+type Source interface {
+	// Lookup returns the value of the variable named by the key and whether it is present.
+	Lookup(key string) (string, bool)
+	// Keys returns the names of all the variables present in the source.
+	Keys() []string
+}
+
+// OSSource is a [Source] backed by the process environment.
+type OSSource struct{}
+
+// Lookup implements [Source].
+func (OSSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// Keys implements [Source].
+func (OSSource) Keys() []string {
+	environ := os.Environ()
+	keys := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		k, _, _ := strings.Cut(kv, "=")
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MapSource is a [Source] backed by a map.
+type MapSource map[string]string
+
+// Lookup implements [Source].
+func (m MapSource) Lookup(key string) (string, bool) {
+	value, ok := m[key]
+	return value, ok
+}
+
+// Keys implements [Source].
+func (m MapSource) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// FileSource parses the named dotenv file into a [Source].
+// The values are kept unexpanded.
+func FileSource(filename string) (MapSource, error) {
+	entries, err := readEntries(filename, ParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return entriesSource(entries), nil
+}
+
+// FSSource parses the named dotenv file of the file system into a [Source].
+// The values are kept unexpanded.
+func FSSource(fsys fs.FS, name string) (MapSource, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := parseEntries(f, ParseOptions{Filename: name})
+	if err != nil {
+		return nil, err
+	}
+	return entriesSource(entries), nil
+}
+
+func entriesSource(entries []entry) MapSource {
+	vars := make(MapSource, len(entries))
+	for _, ent := range entries {
+		vars[ent.key] = ent.value
+	}
+	return vars
+}
+
+// Chain is a [Source] which consults its sources in order.
+// A variable present in an earlier source shadows the same variable in the later ones,
+// so sources are listed from the highest priority to the lowest:
+//
+//	Chain{flags, OSSource{}, local, dotenv, defaults}
+type Chain []Source
+
+// Lookup implements [Source].
+func (c Chain) Lookup(key string) (string, bool) {
+	for _, src := range c {
+		if value, ok := src.Lookup(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Keys implements [Source].
+func (c Chain) Keys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, src := range c {
+		for _, k := range src.Keys() {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// ToMap returns a copy of all the variables present in the source.
+func ToMap(src Source) map[string]string {
+	keys := src.Keys()
+	vars := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if value, ok := src.Lookup(k); ok {
+			vars[k] = value
+		}
+	}
+	return vars
+}
+
+// errLookuper is implemented by the sources whose lookups may fail, such as an [Env] resolving secret files.
+type errLookuper interface {
+	lookupErr(key string) (string, bool, error)
+}
+
+// lookupErr looks up the key in the source, and returns the error of the lookup if it may fail.
+func lookupErr(src Source, key string) (string, bool, error) {
+	if src, ok := src.(errLookuper); ok {
+		return src.lookupErr(key)
+	}
+	value, ok := src.Lookup(key)
+	return value, ok, nil
+}
+
+// toMapErr is like ToMap but returns the error of the first lookup which fails.
+func toMapErr(src Source) (map[string]string, error) {
+	keys := src.Keys()
+	vars := make(map[string]string, len(keys))
+	for _, k := range keys {
+		value, ok, err := lookupErr(src, k)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			vars[k] = value
+		}
+	}
+	return vars, nil
+}
+
+// ExpandFrom expands the variables in the given string using the values of the source.
+// See [Expand] for the supported syntax.
+func ExpandFrom(src Source, s string) string {
+	return ExpandWith(s, src.Lookup)
+}
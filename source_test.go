@@ -0,0 +1,59 @@
+package env
+
+import (
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource(t *testing.T) {
+	t.Run("os", func(t *testing.T) {
+		t.Setenv("TEST_ENV_KEY", "test_value")
+		value, ok := OSSource{}.Lookup("TEST_ENV_KEY")
+		assert.True(t, ok)
+		assert.Equal(t, "test_value", value)
+		assert.Contains(t, OSSource{}.Keys(), "TEST_ENV_KEY")
+	})
+
+	t.Run("file", func(t *testing.T) {
+		src, err := FileSource("testdata/.env")
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost", src["DB_HOST"])
+		assert.Equal(t, "root", src["DB_USER"])
+	})
+
+	t.Run("fs", func(t *testing.T) {
+		fsys := fstest.MapFS{".env": {Data: []byte("A=1\nB=2\n")}}
+		src, err := FSSource(fsys, ".env")
+		assert.NoError(t, err)
+		assert.Equal(t, MapSource{"A": "1", "B": "2"}, src)
+		_, err = FSSource(fsys, "missing")
+		assert.Error(t, err)
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		chain := Chain{
+			MapSource{"PORT": "9090"},
+			MapSource{"PORT": "8080", "HOST": "localhost"},
+		}
+		assert.Equal(t, 9090, MustGetTFrom(chain, "PORT", strconv.Atoi))
+		value, err := GetTOrFrom(chain, "TIMEOUT", strconv.Atoi, 30)
+		assert.NoError(t, err)
+		assert.Equal(t, 30, value)
+		assert.Equal(t, "localhost:9090", ExpandFrom(chain, "${HOST}:${PORT}"))
+		assert.ElementsMatch(t, []string{"PORT", "HOST"}, chain.Keys())
+		assert.Equal(t, map[string]string{"PORT": "9090", "HOST": "localhost"}, ToMap(chain))
+	})
+
+	t.Run("env over chain", func(t *testing.T) {
+		e := NewFrom(Chain{MapSource{"A": "1"}, MapSource{"B": "2"}})
+		assert.NoError(t, e.Set("A", "3"))
+		assert.Equal(t, "3", e.Get("A"))
+		assert.Equal(t, "2", e.Get("B"))
+		assert.NoError(t, e.Unset("B"))
+		_, ok := e.Lookup("B")
+		assert.False(t, ok)
+	})
+}
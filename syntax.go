@@ -0,0 +1,352 @@
+package env
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// node is a part of a string to expand, either literal text or a reference.
+type node struct {
+	text string
+	ref  *reference
+}
+
+// reference is a $ENV_KEY or ${...} reference.
+type reference struct {
+	// offset is the byte offset of the $ in the expanded string, and raw the text of the reference.
+	offset int
+	raw    string
+
+	// pipe reports a reference written with the pipe syntax,
+	// which looks up the keys in order and falls back to the default when it has one.
+	pipe        bool
+	keys        []string
+	fallback    []node
+	withDefault bool
+
+	// name is the variable of a reference written with the shell syntax,
+	// op the operator following the name, one of
+	// "" - :- = := ? :? + :+ # ## % %% ^ ^^ , ,, or ":" for a substring,
+	// and word the operand of the operator.
+	name string
+	op   string
+	word []node
+	// start and length are the bounds of a substring.
+	start, length int
+	withLength    bool
+	// isLength reports a ${#ENV_KEY} reference.
+	isLength bool
+}
+
+// parser parses the references of a string.
+//
+// Outside of references, $$ and \$ are escapes for a literal $.
+// Inside of a ${...} reference, a backslash escapes any character, and braces may be nested.
+type parser struct {
+	src     string
+	dialect Dialect
+	errs    []error
+}
+
+// parse parses s into nodes, and returns a [*SyntaxError] for every reference which cannot be parsed.
+// It never fails: the references in error expand to nothing.
+func parse(s string, dialect Dialect) ([]node, []error) {
+	p := &parser{src: s, dialect: dialect}
+	return p.parse(0, len(s), false), p.errs
+}
+
+// parse parses src[start:end], which is the operand of a reference if inWord is true.
+func (p *parser) parse(start, end int, inWord bool) []node {
+	var nodes []node
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, node{text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := start; i < end; {
+		c := p.src[i]
+		switch {
+		case c == '\\' && i+1 < end && (inWord || p.src[i+1] == '$'):
+			text.WriteByte(p.src[i+1])
+			i += 2
+		case c != '$' || i+1 == end:
+			text.WriteByte(c)
+			i++
+		case p.src[i+1] == '$':
+			text.WriteByte('$')
+			i += 2
+		case p.src[i+1] == '{':
+			closing := p.closingBrace(i+2, end)
+			if closing < 0 {
+				p.syntaxError(i, end, "unterminated reference")
+				text.WriteString(p.src[i:end])
+				i = end
+				continue
+			}
+			flush()
+			if ref := p.braced(i, closing); ref != nil {
+				nodes = append(nodes, node{ref: ref})
+			}
+			i = closing + 1
+		default:
+			n := bareNameLen(p.src[i+1 : end])
+			if n == 0 {
+				text.WriteByte(c)
+				i++
+				continue
+			}
+			flush()
+			nodes = append(nodes, node{ref: &reference{offset: i, raw: p.src[i : i+1+n], name: p.src[i+1 : i+1+n]}})
+			i += 1 + n
+		}
+	}
+	flush()
+	return nodes
+}
+
+// closingBrace returns the index of the brace closing the reference whose content starts at start, or -1.
+func (p *parser) closingBrace(start, end int) int {
+	depth := 1
+	for i := start; i < end; i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '$':
+			if i+1 < end && p.src[i+1] == '{' {
+				depth++
+				i++
+			}
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// braced parses the ${...} reference of src[start:closing+1].
+func (p *parser) braced(start, closing int) *reference {
+	ref := &reference{offset: start, raw: p.src[start : closing+1]}
+	body := p.src[start+2 : closing]
+	if strings.TrimSpace(body) == "" {
+		p.syntaxError(start, closing+1, "empty reference")
+		return nil
+	}
+	if p.dialect == DialectPipe || p.dialect == DialectAuto && p.separator(start+2, closing) >= 0 {
+		p.pipe(ref, start+2, closing)
+		return ref
+	}
+	if msg := p.shell(ref, start+2, closing); msg != "" {
+		if p.dialect == DialectAuto {
+			p.pipe(ref, start+2, closing)
+			return ref
+		}
+		p.syntaxError(start, closing+1, msg)
+		return nil
+	}
+	return ref
+}
+
+// separator returns the index of the first | of src[start:end] out of nested references, or -1.
+func (p *parser) separator(start, end int) int {
+	depth := 0
+	for i := start; i < end; i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '$':
+			if i+1 < end && p.src[i+1] == '{' {
+				depth++
+				i++
+			}
+		case '}':
+			depth--
+		case '|':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// pipe parses the content src[start:end] of a reference written with the pipe syntax.
+func (p *parser) pipe(ref *reference, start, end int) {
+	ref.pipe = true
+	for {
+		i := p.separator(start, end)
+		if i < 0 {
+			break
+		}
+		if key := unescape(strings.TrimSpace(p.src[start:i])); key != "" {
+			ref.keys = append(ref.keys, key)
+		}
+		start = i + 1
+		ref.withDefault = true
+	}
+	if ref.withDefault {
+		ref.fallback = p.parse(start, end, true)
+	} else if key := unescape(strings.TrimSpace(p.src[start:end])); key != "" {
+		ref.keys = append(ref.keys, key)
+	}
+}
+
+// shell parses the content src[start:end] of a reference written with the shell syntax,
+// and returns a message describing the error if it is not valid.
+func (p *parser) shell(ref *reference, start, end int) string {
+	body := p.src[start:end]
+	if len(body) > 1 && body[0] == '#' {
+		if shellNameLen(body[1:]) != len(body)-1 {
+			return fmt.Sprintf("invalid variable name %q", body[1:])
+		}
+		ref.name, ref.isLength = body[1:], true
+		return ""
+	}
+	n := shellNameLen(body)
+	if n == 0 {
+		return "missing variable name"
+	}
+	ref.name = body[:n]
+	rest := body[n:]
+	wordStart := -1
+	switch {
+	case rest == "":
+	case hasAnyPrefix(rest, ":-", ":=", ":?", ":+", "##", "%%", "^^", ",,"):
+		ref.op, wordStart = rest[:2], start+n+2
+	case strings.IndexByte("-=?+#%^,", rest[0]) >= 0:
+		ref.op, wordStart = rest[:1], start+n+1
+	case rest[0] == ':':
+		ref.op = ":"
+		offset, length, withLength := strings.Cut(rest[1:], ":")
+		var err error
+		if ref.start, err = strconv.Atoi(strings.TrimSpace(offset)); err != nil {
+			return fmt.Sprintf("invalid substring offset %q", offset)
+		}
+		if withLength {
+			if ref.length, err = strconv.Atoi(strings.TrimSpace(length)); err != nil {
+				return fmt.Sprintf("invalid substring length %q", length)
+			}
+			ref.withLength = true
+		}
+	default:
+		return fmt.Sprintf("unexpected %q after variable name", rest)
+	}
+	switch ref.op {
+	case "^", "^^", ",", ",,":
+		if wordStart < end {
+			return "case modification patterns are not supported"
+		}
+	}
+	if wordStart >= 0 {
+		ref.word = p.parse(wordStart, end, true)
+	}
+	return ""
+}
+
+func (p *parser) syntaxError(start, end int, msg string) {
+	line, column := position(p.src, start)
+	p.errs = append(p.errs, &SyntaxError{
+		Reference: p.src[start:end],
+		Offset:    start,
+		Line:      line,
+		Column:    column,
+		Msg:       msg,
+	})
+}
+
+// unescape removes the backslashes escaping characters.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// bareNameLen returns the length of the name of a $ENV_KEY reference at the beginning of s.
+// Like [os.Expand], it accepts a single special character or digit as name.
+func bareNameLen(s string) int {
+	if s == "" {
+		return 0
+	}
+	if isShellSpecialVar(s[0]) {
+		return 1
+	}
+	var i int
+	for i = 0; i < len(s) && isAlphaNum(s[i]); i++ {
+	}
+	return i
+}
+
+// shellNameLen returns the length of the variable name at the beginning of the content of a reference.
+func shellNameLen(s string) int {
+	if s == "" {
+		return 0
+	}
+	if isShellSpecialVar(s[0]) && (s[0] < '0' || s[0] > '9') {
+		return 1
+	}
+	var i int
+	for i = 0; i < len(s) && isAlphaNum(s[i]); i++ {
+	}
+	return i
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isShellSpecialVar(c uint8) bool {
+	switch c {
+	case '*', '#', '$', '@', '!', '?', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
+func isAlphaNum(c uint8) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// position returns the 1-based line and column of the byte offset in s.
+func position(s string, offset int) (int, int) {
+	line := 1 + strings.Count(s[:offset], "\n")
+	return line, offset - strings.LastIndexByte(s[:offset], '\n')
+}
+
+// references returns the keys referenced by the given string, in order of appearance.
+func references(s string, dialect Dialect) []string {
+	nodes, _ := parse(s, dialect)
+	var keys []string
+	var walk func(nodes []node)
+	walk = func(nodes []node) {
+		for _, n := range nodes {
+			switch {
+			case n.ref == nil:
+			case n.ref.pipe:
+				keys = append(keys, n.ref.keys...)
+				walk(n.ref.fallback)
+			default:
+				keys = append(keys, n.ref.name)
+				walk(n.ref.word)
+			}
+		}
+	}
+	walk(nodes)
+	return keys
+}
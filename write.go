@@ -0,0 +1,131 @@
+package env
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WriteOptions configures the writing of dotenv files.
+type WriteOptions struct {
+	// Order lists the keys in the order they are written.
+	// The keys which are not listed are written after them, sorted. All the keys are sorted when it is empty.
+	Order []string
+	// Export prefixes every line with `export `, so that the file can be sourced by a shell.
+	Export bool
+	// Perm is the permission of the file created by [WriteFile], 0600 when zero.
+	// An existing file keeps its permission.
+	Perm fs.FileMode
+}
+
+// Marshal returns the dotenv file holding the given variables.
+//
+// The values are quoted when needed so that loading the file gives them back as they are:
+// they are single-quoted when they hold a $, which is not expanded then,
+// and double-quoted with escape sequences when they hold newlines, quotes or other special characters.
+func Marshal(m map[string]string, opts WriteOptions) (string, error) {
+	keys := make([]string, 0, len(m))
+	for _, key := range opts.Order {
+		if _, ok := m[key]; ok && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	ordered := len(keys)
+	for key := range m {
+		if !slices.Contains(keys[:ordered], key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys[ordered:])
+	var b strings.Builder
+	for _, key := range keys {
+		if err := checkDotenvKey(key); err != nil {
+			return "", err
+		}
+		if opts.Export {
+			b.WriteString("export ")
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(quoteValue(m[key]))
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// Write writes the dotenv file holding the given variables to w, see [Marshal].
+//
+// The current environment is written with:
+//
+//	env.Write(w, env.ToMap(env.Default()), env.WriteOptions{})
+func Write(w io.Writer, m map[string]string, opts WriteOptions) error {
+	s, err := Marshal(m, opts)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, s)
+	return err
+}
+
+// WriteFile writes the dotenv file holding the given variables to the named file, see [Marshal].
+// The file is replaced atomically: it is written to a temporary file which is then renamed.
+func WriteFile(filename string, m map[string]string, opts WriteOptions) error {
+	s, err := Marshal(m, opts)
+	if err != nil {
+		return err
+	}
+	perm := opts.Perm
+	if perm == 0 {
+		perm = 0o600
+	}
+	if info, err := os.Stat(filename); err == nil {
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return writeFileAtomic(filename, []byte(s), perm)
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of the named file, and renames it.
+func writeFileAtomic(filename string, data []byte, perm fs.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// quoteValue formats a value so that loading it gives back the value.
+// Single quotes are preferred for the values holding a $, since they are read literally even when the values are not expanded.
+func quoteValue(value string) string {
+	dollar := strings.Contains(value, "$")
+	switch {
+	case isBareValue(value):
+		return value
+	case dollar && !strings.ContainsAny(value, "'\r\n"):
+		return "'" + value + "'"
+	}
+	return doubleQuote(value)
+}
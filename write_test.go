@@ -0,0 +1,97 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	vars := map[string]string{
+		"PLAIN":     "localhost:5432",
+		"EMPTY":     "",
+		"SPACES":    " a b ",
+		"NEWLINES":  "line1\nline2\r\n",
+		"QUOTES":    `it's "quoted"`,
+		"BACKSLASH": `C:\path\n`,
+		"COMMENT":   "a #b",
+		"DOLLAR":    "$HOME ${USER|root}",
+		"MIXED":     "it's $HOME\n",
+		"UNICODE":   "héllo",
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		s, err := Marshal(vars, WriteOptions{})
+		assert.NoError(t, err)
+		e := New()
+		dir := t.TempDir()
+		filename := filepath.Join(dir, ".env")
+		assert.NoError(t, os.WriteFile(filename, []byte(s), 0o600))
+		assert.NoError(t, e.Load(filename))
+		assert.Equal(t, vars, e.Map())
+
+		parsed, err := Parse(strings.NewReader(s), ParseOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, vars, parsed)
+
+		e = New()
+		assert.NoError(t, e.LoadWithOptions(LoadOptions{}, filename))
+		assert.Equal(t, vars, e.Map())
+	})
+
+	t.Run("quoting", func(t *testing.T) {
+		s, err := Marshal(vars, WriteOptions{Order: []string{"PLAIN", "EMPTY", "MISSING"}})
+		assert.NoError(t, err)
+		assert.Equal(t, `PLAIN=localhost:5432
+EMPTY=
+BACKSLASH="C:\\path\\n"
+COMMENT="a #b"
+DOLLAR='$HOME ${USER|root}'
+MIXED="it's \$HOME\n"
+NEWLINES="line1\nline2\r\n"
+QUOTES="it's \"quoted\""
+SPACES=" a b "
+UNICODE="héllo"
+`, s)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		var b strings.Builder
+		assert.NoError(t, Write(&b, map[string]string{"B": "2", "A": "1"}, WriteOptions{Export: true}))
+		assert.Equal(t, "export A=1\nexport B=2\n", b.String())
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		_, err := Marshal(map[string]string{"A B": "1"}, WriteOptions{})
+		assert.EqualError(t, err, `env: invalid key "A B"`)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		filename := filepath.Join(dir, ".env")
+		assert.NoError(t, WriteFile(filename, map[string]string{"A": "1"}, WriteOptions{}))
+		data, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, "A=1\n", string(data))
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(filename)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+			assert.NoError(t, os.Chmod(filename, 0o640))
+			assert.NoError(t, WriteFile(filename, map[string]string{"A": "2"}, WriteOptions{}))
+			info, err = os.Stat(filename)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		}
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Error(t, WriteFile(filepath.Join(dir, "missing", ".env"), map[string]string{"A": "1"}, WriteOptions{}))
+	})
+}